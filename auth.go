@@ -0,0 +1,364 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+const (
+	// ErrLoginFailed is returned if Worker.Login could not log in.
+	ErrLoginFailed = Error("login failed")
+
+	// ErrSessionExpired is the cause of requests retried after logging
+	// in again.
+	ErrSessionExpired = Error("session expired")
+)
+
+// Credentials authenticate the requests to a host.
+type Credentials struct {
+	// Username and Password are sent using HTTP Basic authentication if
+	// Username is not empty.
+	Username string
+	Password string
+
+	// Token is sent as bearer token if not empty.
+	Token string
+
+	// Header holds custom headers such as API keys.
+	Header http.Header
+}
+
+func (c *Credentials) apply(req *http.Request) {
+	if len(c.Username) > 0 {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	if len(c.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	for key, values := range c.Header {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+}
+
+// hostTransport applies the credentials and headers of the request
+// host. Applying them in the transport instead of the request keeps them
+// on redirects within the host and off redirects to other hosts.
+type hostTransport struct {
+	credentials map[string]*Credentials
+	header      map[string]http.Header
+	base        http.RoundTripper
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.credentials[req.URL.Host]
+	if c == nil {
+		c = t.credentials[req.URL.Hostname()]
+	}
+	h, found := t.header[req.URL.Host]
+	if !found {
+		h = t.header[req.URL.Hostname()]
+	}
+	if c == nil && len(h) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for key, values := range h {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	if c != nil {
+		c.apply(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Login describes a scripted form login. The login page is fetched, the
+// form is filled with the default values of its fields and Fields, and
+// submitted. The session cookies are kept in Worker.Jar.
+type Login struct {
+	// URL is the URL of the page holding the login form.
+	URL *url.URL
+
+	// Form is a CSS selector matching the login form. If empty the first
+	// form with a password field is used.
+	Form string
+
+	// Fields holds the submitted values, such as the username and
+	// password, overriding the values of the form.
+	Fields url.Values
+
+	// Success is a CSS selector which must match the page returned by
+	// the form submission, such as a logout link. If empty the login
+	// succeeds if the page is returned with status 200 and contains no
+	// password field.
+	Success string
+
+	// Expired is a CSS selector matching pages served to logged out
+	// users. A session is also expired if a request is redirected to
+	// the login page or answered with 401 Unauthorized.
+	Expired string
+
+	mu         sync.Mutex
+	generation int // number of successful logins
+}
+
+// login logs in using the client of w unless a login after generation
+// already succeeded.
+func (l *Login) login(w *Worker, generation int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.generation > generation {
+		return nil // logged in again by another worker
+	}
+
+	form, err := compileSelector(l.Form, "form:has(input[type=password])")
+	if err != nil {
+		return fmt.Errorf("%w: form selector: %v", ErrLoginFailed, err)
+	}
+	success, err := compileSelector(l.Success, "")
+	if err != nil {
+		return fmt.Errorf("%w: success selector: %v", ErrLoginFailed, err)
+	}
+
+	client := w.client()
+	page, node, err := l.do(client, w.header(""), "GET", l.URL, nil)
+	if err != nil {
+		return err
+	}
+	n := cascadia.Query(node, form)
+	if n == nil {
+		return fmt.Errorf("%w: no login form on %s", ErrLoginFailed, page)
+	}
+	action, method, values := formValues(page, n)
+	for key, v := range l.Fields {
+		values[key] = v
+	}
+
+	var body io.Reader
+	if method == "POST" {
+		body = strings.NewReader(values.Encode())
+	} else {
+		action.RawQuery = values.Encode()
+	}
+	if _, node, err = l.do(client, w.header(page.String()), method, action, body); err != nil {
+		return err
+	}
+	if success != nil && cascadia.Query(node, success) == nil {
+		return fmt.Errorf("%w: %q not found after login", ErrLoginFailed, l.Success)
+	}
+	if success == nil && find(node, isPasswordField) != nil {
+		return fmt.Errorf("%w: login form returned again", ErrLoginFailed)
+	}
+	l.generation++
+	return nil
+}
+
+// do issues a login request and returns the final URL and the parsed
+// response.
+func (l *Login) do(client *http.Client, header http.Header, method string, url *url.URL, body io.Reader) (*url.URL, *html.Node, error) {
+	req, err := http.NewRequest(method, url.String(), body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, values := range header {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	if len(req.Header.Get("User-Agent")) == 0 {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, nil, fmt.Errorf("%w: %s %s: %s", ErrLoginFailed, method, url, resp.Status)
+	}
+	node, err := html.Parse(resp.Body)
+	if err != nil {
+		return nil, nil, &ParseError{err}
+	}
+	return resp.Request.URL, node, nil
+}
+
+func compileSelector(sel, def string) (cascadia.Sel, error) {
+	if len(sel) == 0 {
+		sel = def
+	}
+	if len(sel) == 0 {
+		return nil, nil
+	}
+	return cascadia.Parse(sel)
+}
+
+func isPasswordField(n *html.Node) bool {
+	return n.Data == "input" && strings.EqualFold(attr(n, "type"), "password")
+}
+
+// formValues returns the resolved action, the method and the values
+// submitted by default with the form element of the page.
+func formValues(page *url.URL, form *html.Node) (*url.URL, string, url.Values) {
+	action := page
+	if a := strings.TrimSpace(attr(form, "action")); len(a) > 0 {
+		if u, err := page.Parse(a); err == nil {
+			action = u
+		}
+	}
+	action = &url.URL{
+		Scheme:   action.Scheme,
+		User:     action.User,
+		Host:     action.Host,
+		Path:     action.Path,
+		RawPath:  action.RawPath,
+		RawQuery: action.RawQuery,
+	}
+	method := "GET"
+	if strings.EqualFold(attr(form, "method"), "post") {
+		method = "POST"
+	}
+
+	values := url.Values{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			name := attr(n, "name")
+			switch n.Data {
+			case "input":
+				switch strings.ToLower(attr(n, "type")) {
+				case "submit", "button", "image", "reset", "file":
+				case "checkbox", "radio":
+					if hasAttr(n, "checked") && len(name) > 0 {
+						value := attr(n, "value")
+						if !hasAttr(n, "value") {
+							value = "on"
+						}
+						values.Add(name, value)
+					}
+				default:
+					if len(name) > 0 {
+						values.Add(name, attr(n, "value"))
+					}
+				}
+			case "textarea":
+				if len(name) > 0 {
+					values.Add(name, text(n))
+				}
+			case "select":
+				if len(name) > 0 {
+					if o := find(n, func(o *html.Node) bool { return o.Data == "option" && hasAttr(o, "selected") }); o != nil {
+						values.Add(name, optionValue(o))
+					} else if o := find(n, func(o *html.Node) bool { return o.Data == "option" }); o != nil {
+						values.Add(name, optionValue(o))
+					}
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(form)
+	return action, method, values
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func optionValue(o *html.Node) string {
+	if hasAttr(o, "value") {
+		return attr(o, "value")
+	}
+	return strings.TrimSpace(text(o))
+}
+
+// isLoginPage reports whether url refers to the login page, ignoring
+// the query which often holds the page to return to.
+func (l *Login) isLoginPage(url *url.URL) bool {
+	return url.Host == l.URL.Host && url.Path == l.URL.Path
+}
+
+// expired reports whether the response to a request of url shows that
+// the session expired. If Expired is set the body is read and expired
+// returns the body to use instead.
+func (l *Login) expired(url *url.URL, body io.ReadCloser, err error) (io.ReadCloser, bool, error) {
+	if l.isLoginPage(url) {
+		return body, false, err
+	}
+	if e, ok := err.(*StatusError); ok && e.Code == http.StatusUnauthorized {
+		return body, true, err
+	}
+	if err != nil {
+		return body, false, err
+	}
+	resp, ok := body.(*Response)
+	if ok && resp.Request != nil && l.isLoginPage(resp.Request.URL) {
+		return body, true, nil
+	}
+	sel, err := compileSelector(l.Expired, "")
+	if sel == nil || err != nil {
+		return body, false, nil
+	}
+
+	data, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	} else {
+		body = ioutil.NopCloser(bytes.NewReader(data))
+	}
+	node, err := parseHTML(data)
+	return body, err == nil && cascadia.Query(node, sel) != nil, nil
+}
+
+// get fetches url. If the session of Worker.Login expired, get logs in
+// again and retries the request once.
+func (w *worker) get(url *url.URL) (io.ReadCloser, error) {
+	ctx := withRetry(context.Background(), w.retry)
+	referrer := w.origins.get(url).referrer
+	l := w.w.Login
+	if l == nil {
+		return w.w.get(ctx, url, referrer)
+	}
+	l.mu.Lock()
+	generation := l.generation
+	l.mu.Unlock()
+
+	body, err := w.w.get(ctx, url, referrer)
+	body, expired, err := l.expired(url, body, err)
+	if !expired {
+		return body, err
+	}
+	if body != nil {
+		body.Close()
+	}
+	w.log(slog.LevelInfo, "session expired, logging in", "url", url.String())
+	if err = l.login(w.w, generation); err != nil {
+		return nil, err
+	}
+	w.retry(url, ErrSessionExpired)
+	return w.w.get(ctx, url, referrer)
+}
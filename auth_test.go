@@ -0,0 +1,247 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCredentials(t *testing.T) {
+	t.Parallel()
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "" || req.Header.Get("X-Api-Key") != "" {
+			http.Error(w, "leaked credentials", http.StatusBadRequest)
+		}
+	}))
+	defer other.Close()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/basic":
+			if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+			}
+		case "/token":
+			if req.Header.Get("Authorization") != "Bearer t0k3n" || req.Header.Get("X-Api-Key") != "key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+			}
+		case "/redirect":
+			http.Redirect(w, req, "/token", http.StatusFound)
+		case "/away":
+			http.Redirect(w, req, other.URL, http.StatusFound)
+		}
+	}))
+	defer s.Close()
+
+	host := mustParseURL(s.URL).Host
+	w := &Worker{Credentials: map[string]*Credentials{
+		host: {Username: "user", Password: "secret"},
+	}}
+	if _, err := w.Get(mustParseURL(s.URL + "/basic")); err != nil {
+		t.Fatalf("credentials: basic auth: %v", err)
+	}
+
+	w.Credentials[host] = &Credentials{Token: "t0k3n", Header: http.Header{"x-api-key": {"key"}}}
+	for _, path := range []string{"/token", "/redirect", "/away"} {
+		if _, err := w.Get(mustParseURL(s.URL + path)); err != nil {
+			t.Fatalf("credentials: %s: %v", path, err)
+		}
+	}
+	if _, err := (&Worker{}).Get(mustParseURL(s.URL + "/token")); err == nil || err.Error() != "401 Unauthorized" {
+		t.Fatalf("credentials: expected 401 Unauthorized, got %v", err)
+	}
+}
+
+func TestSitemapCredentials(t *testing.T) {
+	t.Parallel()
+
+	var sitemap string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "secret" || req.Header.Get("X-Crawl") != "1" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if req.URL.Path == "/sitemap.xml" {
+			w.Write([]byte(sitemap))
+			return
+		}
+		w.Write([]byte(`<html><body></body></html>`))
+	}))
+	defer s.Close()
+	sitemap = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>` + s.URL + `/a</loc></url><url><loc>` + s.URL + `/b</loc></url></urlset>`
+
+	w := newTestWorker()
+	w.GetFunc = nil
+	w.Host = mustParseURL(s.URL)
+	w.Header = http.Header{"X-Crawl": {"1"}}
+	w.Credentials = map[string]*Credentials{w.Host.Host: {Username: "user", Password: "secret"}}
+	var mu sync.Mutex
+	var fetched []string
+	w.PageFunc = func(page *Page) {
+		mu.Lock()
+		fetched = append(fetched, page.URL.Path)
+		mu.Unlock()
+	}
+
+	c := New(w, time.Millisecond*50, nil)
+	if err := c.Start(mustParseURL(s.URL + "/sitemap.xml")); err != nil {
+		t.Fatalf("sitemap: %v", err)
+	}
+	<-c.Done()
+	sort.Strings(fetched)
+	if strings.Join(fetched, " ") != "/a /b" {
+		t.Fatalf("sitemap: unexpected fetched pages %v", fetched)
+	}
+}
+
+// loginServer is a site behind a form login whose sessions expire after
+// a number of requests.
+type loginServer struct {
+	mu       sync.Mutex
+	logins   int
+	sessions map[string]int // remaining requests of a session
+}
+
+func (s *loginServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.URL.Path == "/login" {
+		if req.Method == "POST" && req.FormValue("csrf") == "42" && req.FormValue("remember") == "on" &&
+			req.FormValue("user") == "ann" && req.FormValue("password") == "secret" {
+			s.logins++
+			session := fmt.Sprint(s.logins)
+			s.sessions[session] = 3
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: session, Path: "/"})
+			http.Redirect(w, req, "/", http.StatusFound)
+			return
+		}
+		w.Write([]byte(`<html><body><form method="post" action="/login">
+			<input type="hidden" name="csrf" value="42">
+			<input name="user"> <input type="password" name="password">
+			<input type="checkbox" name="remember" checked>
+			<input type="submit" name="go" value="Log in">
+		</form></body></html>`))
+		return
+	}
+
+	c, err := req.Cookie("session")
+	if err != nil || s.sessions[c.Value] <= 0 {
+		http.Redirect(w, req, "/login?next="+url.QueryEscape(req.URL.Path), http.StatusFound)
+		return
+	}
+	s.sessions[c.Value]--
+	w.Write([]byte(`<html><body><a href="/logout">Log out</a>` +
+		`<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a><a href="/d">d</a></body></html>`))
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	ls := &loginServer{sessions: make(map[string]int)}
+	s := httptest.NewServer(ls)
+	defer s.Close()
+
+	w := newTestWorker()
+	w.GetFunc = nil
+	w.Concurrent = 1
+	w.Host = mustParseURL(s.URL)
+	w.Reject = []*regexp.Regexp{regexp.MustCompile("/logout$")}
+	w.Login = &Login{
+		URL:     mustParseURL(s.URL + "/login"),
+		Fields:  url.Values{"user": {"ann"}, "password": {"secret"}},
+		Success: `a[href="/logout"]`,
+	}
+	var mu sync.Mutex
+	var fetched []string
+	w.PageFunc = func(page *Page) {
+		mu.Lock()
+		fetched = append(fetched, page.URL.Path)
+		mu.Unlock()
+	}
+
+	c := New(w, time.Millisecond*50, nil)
+	if err := c.Start(nil, mustParseURL(s.URL+"/")); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	<-c.Done()
+
+	sort.Strings(fetched)
+	if strings.Join(fetched, " ") != "/ /a /b /c /d" {
+		t.Fatalf("login: unexpected fetched pages %v", fetched)
+	}
+	// the login before seeding and one per expired session
+	if ls.logins != 3 {
+		t.Fatalf("login: expected 3 logins, got %d", ls.logins)
+	}
+	if n := c.Stats().Retries; n != 2 {
+		t.Fatalf("login: expected 2 retries, got %d", n)
+	}
+}
+
+func TestLoginFailed(t *testing.T) {
+	t.Parallel()
+
+	s := httptest.NewServer(&loginServer{sessions: make(map[string]int)})
+	defer s.Close()
+
+	for _, login := range []*Login{
+		{URL: mustParseURL(s.URL + "/login"), Fields: url.Values{"user": {"ann"}, "password": {"wrong"}}},
+		{URL: mustParseURL(s.URL + "/login"), Form: "form#missing"},
+		{URL: mustParseURL(s.URL + "/missing")},
+	} {
+		w := newTestWorker()
+		w.GetFunc = nil
+		w.Host = mustParseURL(s.URL)
+		w.Login = login
+		c := New(w, time.Millisecond*20, nil)
+		err := c.Start(nil, w.Host)
+		if !errors.Is(err, ErrLoginFailed) {
+			t.Fatalf("login: expected %v, got %v", ErrLoginFailed, err)
+		}
+		c.Close()
+		<-c.Done()
+	}
+}
+
+func TestLoginExpired(t *testing.T) {
+	l := &Login{URL: mustParseURL("http://example.com/login"), Expired: "form.login"}
+	for _, test := range []struct {
+		url     string
+		data    string
+		err     error
+		expired bool
+	}{
+		{"http://example.com/a", `<form class="login"></form>`, nil, true},
+		{"http://example.com/a", `<form class="search"></form>`, nil, false},
+		{"http://example.com/login", `<form class="login"></form>`, nil, false},
+		{"http://example.com/a", "", &StatusError{Code: 401, Status: "401 Unauthorized"}, true},
+		{"http://example.com/a", "", &StatusError{Code: 404, Status: "404 Not Found"}, false},
+	} {
+		var body io.ReadCloser
+		if test.err == nil {
+			body = ioutil.NopCloser(strings.NewReader(test.data))
+		}
+		body, expired, err := l.expired(mustParseURL(test.url), body, test.err)
+		if expired != test.expired || err != test.err {
+			t.Fatalf("expired %s %q: expected %v, got %v (%v)", test.url, test.data, test.expired, expired, err)
+		}
+		if body != nil {
+			if data, _ := ioutil.ReadAll(body); string(data) != test.data {
+				t.Fatalf("expired: expected body %q, got %q", test.data, data)
+			}
+		}
+	}
+}
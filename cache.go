@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"sync"
+)
+
+// Validator holds the cache validators and the normalized anchor links
+// of a previously fetched URL. Links are filtered by Worker.IsAccepted
+// when they are replayed.
+type Validator struct {
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Links        []string `json:"links,omitempty"`
+}
+
+// Cache stores validators of fetched URLs. It is used to issue
+// conditional requests in incremental crawls.
+type Cache interface {
+	Load(url *url.URL) (Validator, bool)
+	Store(url *url.URL, v Validator)
+}
+
+// MemoryCache is a Cache held in memory. It can be persisted between
+// crawls using WriteTo and ReadFrom.
+type MemoryCache struct {
+	mu  sync.Mutex
+	set map[string]Validator
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{set: make(map[string]Validator)}
+}
+
+func (c *MemoryCache) Load(url *url.URL) (Validator, bool) {
+	c.mu.Lock()
+	v, found := c.set[urlKey(url)]
+	c.mu.Unlock()
+	return v, found
+}
+
+func (c *MemoryCache) Store(url *url.URL, v Validator) {
+	c.mu.Lock()
+	c.set[urlKey(url)] = v
+	c.mu.Unlock()
+}
+
+// Len returns the number of cached URLs.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.set)
+}
+
+// WriteTo writes the cache as JSON to w.
+func (c *MemoryCache) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	data, err := json.Marshal(c.set)
+	c.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadFrom reads a cache written by WriteTo from r and merges it into c.
+func (c *MemoryCache) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countReader{r: r}
+	set := make(map[string]Validator)
+	if err := json.NewDecoder(cr).Decode(&set); err != nil {
+		return cr.n, err
+	}
+
+	c.mu.Lock()
+	for key, v := range set {
+		c.set[key] = v
+	}
+	c.mu.Unlock()
+	return cr.n, nil
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func startETagServer(t *testing.T) *httptest.Server {
+	pages := map[string]string{
+		"/":  `<html><body><a href="/a">a</a><a href="/b">b</a></body></html>`,
+		"/a": `<html><body><a href="/">index</a></body></html>`,
+		"/b": `<html><body><a href="/a">a</a></body></html>`,
+	}
+	h := func(w http.ResponseWriter, req *http.Request) {
+		page, found := pages[req.URL.Path]
+		if !found {
+			http.NotFound(w, req)
+			return
+		}
+		etag := `"` + req.URL.Path + `"`
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(page))
+	}
+	return httptest.NewServer(http.HandlerFunc(h))
+}
+
+func TestCrawlerUnchanged(t *testing.T) {
+	t.Parallel()
+
+	s := startETagServer(t)
+	defer s.Close()
+
+	cache := NewMemoryCache()
+	var stats Stats
+	crawl := func() (processed, unchanged int) {
+		mu := &sync.Mutex{}
+		w := &Worker{
+			ProcessFunc: func(*url.URL, *html.Node, []byte) {
+				mu.Lock()
+				processed++
+				mu.Unlock()
+			},
+			UnchangedFunc: func(*url.URL) {
+				mu.Lock()
+				unchanged++
+				mu.Unlock()
+			},
+			Cache: cache,
+		}
+		w.Host, _ = url.Parse(s.URL)
+
+		c := New(w, time.Millisecond*20, nil)
+		c.Start(nil, w.Host)
+		<-c.Done()
+		stats = c.Stats()
+		return processed, unchanged
+	}
+
+	if processed, unchanged := crawl(); processed != 3 || unchanged != 0 {
+		t.Fatalf("cache: expected 3 processed and 0 unchanged, got %d and %d", processed, unchanged)
+	}
+	if n := cache.Len(); n != 3 {
+		t.Fatalf("cache: expected 3 cached urls, got %d", n)
+	}
+	if processed, unchanged := crawl(); processed != 0 || unchanged != 3 {
+		t.Fatalf("cache: expected 0 processed and 3 unchanged, got %d and %d", processed, unchanged)
+	}
+	if stats.Fetched != 0 || stats.Unchanged != 3 {
+		t.Fatalf("stats: expected 0 fetched and 3 unchanged urls, got %d and %d", stats.Fetched, stats.Unchanged)
+	}
+}
+
+func TestCrawlerUnchangedLinks(t *testing.T) {
+	t.Parallel()
+
+	s := startETagServer(t)
+	defer s.Close()
+
+	cache := NewMemoryCache()
+	crawl := func(reject string) map[string]int {
+		mu := &sync.Mutex{}
+		processed := map[string]int{}
+		w := &Worker{
+			ProcessFunc: func(u *url.URL, _ *html.Node, _ []byte) {
+				mu.Lock()
+				processed[u.Path]++
+				mu.Unlock()
+			},
+			IsAcceptedFunc: func(u *url.URL) bool {
+				return u.Path != reject
+			},
+			Cache: cache,
+		}
+		w.Host, _ = url.Parse(s.URL)
+
+		c := New(w, time.Millisecond*20, nil)
+		c.Start(nil, w.Host)
+		<-c.Done()
+		return processed
+	}
+
+	if processed := crawl("/b"); len(processed) != 2 || processed["/b"] != 0 {
+		t.Fatalf("cache: expected / and /a processed, got %v", processed)
+	}
+	v, _ := cache.Load(mustParseURL(s.URL + "/"))
+	if len(v.Links) != 2 || v.Links[1] != s.URL+"/b" {
+		t.Fatalf("cache: expected rejected link cached, got %v", v.Links)
+	}
+	// the cached link to /b is accepted now, the link to / is rejected
+	if processed := crawl("/"); len(processed) != 1 || processed["/b"] != 1 {
+		t.Fatalf("cache: expected /b processed, got %v", processed)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	u, _ := url.Parse("http://example.com/site?q=1")
+	want := Validator{
+		ETag:         `"abc"`,
+		LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+		Links:        []string{"http://example.com/"},
+	}
+
+	c := NewMemoryCache()
+	c.Store(u, want)
+
+	buf := &bytes.Buffer{}
+	if _, err := c.WriteTo(buf); err != nil {
+		t.Fatalf("cache: write: %v", err)
+	}
+	c = NewMemoryCache()
+	if _, err := c.ReadFrom(buf); err != nil {
+		t.Fatalf("cache: read: %v", err)
+	}
+
+	got, found := c.Load(u)
+	if !found {
+		t.Fatalf("cache: expected cached url %q", u)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("cache: expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,243 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mars9/crawler/graph"
+)
+
+// maxRedirects is the maximum length of a followed redirect chain.
+const maxRedirects = 10
+
+// LinkStatus is the result of checking a single link target.
+type LinkStatus struct {
+	URL    string
+	Method string // request method of the final check, HEAD or GET
+	Status int    // final response status, zero on errors
+	Err    error  // request error
+
+	// Redirects holds the redirect chain from URL to the final URL.
+	Redirects []string
+}
+
+// Broken reports whether the link target is unreachable.
+func (s LinkStatus) Broken() bool {
+	return s.Err != nil || s.Status >= 400
+}
+
+func (s LinkStatus) String() string {
+	if s.Err != nil {
+		return s.Err.Error()
+	}
+	return fmt.Sprintf("%d %s", s.Status, http.StatusText(s.Status))
+}
+
+// BrokenLink is a broken link found on a referring page.
+type BrokenLink struct {
+	graph.Edge
+	Status LinkStatus
+}
+
+// Report lists the broken links of a link graph grouped by referring
+// page.
+type Report struct {
+	Checked int // number of checked link targets
+
+	// Broken holds the broken links per referring page.
+	Broken map[string][]BrokenLink
+}
+
+// Pages returns the sorted referring pages with broken links.
+func (r *Report) Pages() []string {
+	pages := make([]string, 0, len(r.Broken))
+	for page := range r.Broken {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+// WriteTo writes a human readable report to w.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	fmt.Fprintf(cw, "checked %d links, %d pages with broken links\n", r.Checked, len(r.Broken))
+	for _, page := range r.Pages() {
+		fmt.Fprintf(cw, "\n%s\n", page)
+		for _, link := range r.Broken[page] {
+			fmt.Fprintf(cw, "\t%s: %s %q", link.Status, link.URL, link.Text)
+			for _, u := range link.Status.Redirects {
+				fmt.Fprintf(cw, " -> %s", u)
+			}
+			fmt.Fprintln(cw)
+		}
+	}
+	return cw.n, cw.err
+}
+
+// Checker checks the targets of a link graph, including links to other
+// hosts, without following them further. Targets are checked with a HEAD
+// request, falling back to GET if HEAD fails.
+type Checker struct {
+	// Client is used to issue requests. Its Jar and Transport are used,
+	// redirects are followed by the checker. If nil, http.DefaultClient
+	// is used.
+	Client *http.Client
+
+	// HeaderFunc returns the header sent with a request if set.
+	HeaderFunc func() http.Header
+
+	// UserAgent defines the user-agent string to use for requests. It
+	// overrides the User-Agent returned by HeaderFunc.
+	UserAgent string
+
+	// Delay defines the delay between the checks of a single goroutine.
+	Delay time.Duration
+
+	// Concurrent defines the number of concurrent requests.
+	Concurrent int
+}
+
+// Checker returns a checker sending its requests like the worker does,
+// through Proxies, with the Jar, Header, user agents, Credentials and
+// HostHeader of the worker and Delay between the checks.
+func (w *Worker) Checker() *Checker {
+	return &Checker{
+		Client:     w.client(),
+		HeaderFunc: func() http.Header { return w.header("") },
+		Delay:      w.Delay,
+		Concurrent: w.Concurrent,
+	}
+}
+
+// Check checks every http and https link target of g and returns the
+// broken links grouped by the URL of the referring page.
+func (c *Checker) Check(g *graph.Graph) *Report {
+	edges := g.Edges()
+	seen := make(map[string]bool)
+	var targets []string
+	for i, e := range edges {
+		if len(e.URL) == 0 {
+			edges[i].URL = e.Target
+		}
+		link := edges[i].URL
+		if !seen[link] {
+			seen[link] = true
+			if u, err := url.Parse(link); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+				targets = append(targets, link)
+			}
+		}
+	}
+
+	n := c.Concurrent
+	if n <= 0 {
+		n = 8
+	}
+	results := make(map[string]LinkStatus, len(targets))
+	mu := &sync.Mutex{}
+	work := make(chan string)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range work {
+				status := c.CheckURL(target)
+				mu.Lock()
+				results[target] = status
+				mu.Unlock()
+				if c.Delay > 0 {
+					time.Sleep(c.Delay)
+				}
+			}
+		}()
+	}
+	for _, target := range targets {
+		work <- target
+	}
+	close(work)
+	wg.Wait()
+
+	r := &Report{Checked: len(targets), Broken: make(map[string][]BrokenLink)}
+	for _, e := range edges {
+		if status, found := results[e.URL]; found && status.Broken() {
+			page := g.URL(e.Source)
+			r.Broken[page] = append(r.Broken[page], BrokenLink{e, status})
+		}
+	}
+	return r
+}
+
+// CheckURL checks the single link target rawurl.
+func (c *Checker) CheckURL(rawurl string) LinkStatus {
+	status := c.request("HEAD", rawurl)
+	if status.Broken() {
+		status = c.request("GET", rawurl)
+	}
+	return status
+}
+
+func (c *Checker) request(method, rawurl string) LinkStatus {
+	status := LinkStatus{URL: rawurl, Method: method}
+
+	req, err := http.NewRequest(method, rawurl, nil)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	if c.HeaderFunc != nil {
+		for key, values := range c.HeaderFunc() {
+			req.Header[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	if len(c.UserAgent) > 0 {
+		req.Header.Set("User-Agent", c.UserAgent)
+	} else if len(req.Header.Get("User-Agent")) == 0 {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			status.Redirects = append(status.Redirects, req.URL.String())
+			if len(via) >= maxRedirects {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	if c.Client != nil {
+		client.Jar, client.Transport, client.Timeout = c.Client.Jar, c.Client.Transport, c.Client.Timeout
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16)) // discard reader
+	resp.Body.Close()
+	status.Status = resp.StatusCode
+	return status
+}
+
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
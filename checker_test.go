@@ -0,0 +1,124 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mars9/crawler/graph"
+)
+
+func TestChecker(t *testing.T) {
+	t.Parallel()
+
+	heads := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/ok", "/dir/":
+			w.Write([]byte("ok"))
+		case "/nohead":
+			if req.Method == "HEAD" {
+				heads++
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Write([]byte("ok"))
+		case "/moved":
+			http.Redirect(w, req, "/gone", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer s.Close()
+
+	g := graph.New()
+	for _, e := range []graph.Edge{
+		{Source: s.URL + "/", Target: s.URL + "/ok", Text: "ok", Kind: "a"},
+		{Source: s.URL + "/", Target: s.URL + "/dir", URL: s.URL + "/dir/", Text: "dir", Kind: "a"},
+		{Source: s.URL + "/", Target: s.URL + "/nohead", Text: "no head", Kind: "a"},
+		{Source: s.URL + "/", Target: s.URL + "/moved", Text: "moved", Kind: "a"},
+		{Source: s.URL + "/a", Target: s.URL + "/moved", Text: "moved again", Kind: "a"},
+		{Source: s.URL + "/a", Target: s.URL + "/missing.png", Kind: "img"},
+		{Source: s.URL + "/a", Target: "mailto:info@example.com", Kind: "a"},
+		{Source: s.URL + "/a", Target: "http://127.0.0.1:0/", Text: "down", Kind: "a"},
+	} {
+		g.Add(e)
+	}
+
+	c := &Checker{Concurrent: 1}
+	r := c.Check(g)
+	if r.Checked != 6 {
+		t.Fatalf("checker: expected 6 checked links, got %d", r.Checked)
+	}
+	if heads != 1 {
+		t.Fatalf("checker: expected 1 HEAD request, got %d", heads)
+	}
+
+	pages := r.Pages()
+	if len(pages) != 2 || pages[0] != s.URL+"/" || pages[1] != s.URL+"/a" {
+		t.Fatalf("checker: unexpected referring pages %v", pages)
+	}
+
+	links := r.Broken[s.URL+"/"]
+	if len(links) != 1 {
+		t.Fatalf("checker: expected 1 broken link, got %v", links)
+	}
+	if st := links[0].Status; st.Status != 404 || st.Method != "GET" || len(st.Redirects) != 1 ||
+		st.Redirects[0] != s.URL+"/gone" || links[0].Text != "moved" {
+		t.Fatalf("checker: unexpected broken link %+v", links[0])
+	}
+
+	links = r.Broken[s.URL+"/a"]
+	if len(links) != 3 {
+		t.Fatalf("checker: expected 3 broken links, got %v", links)
+	}
+	if links[2].Status.Err == nil || links[2].Text != "down" {
+		t.Fatalf("checker: expected request error, got %+v", links[2])
+	}
+
+	buf := &strings.Builder{}
+	if _, err := r.WriteTo(buf); err != nil {
+		t.Fatalf("checker: write report: %v", err)
+	}
+	want := "\t404 Not Found: " + s.URL + `/moved "moved" -> ` + s.URL + "/gone\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("checker: expected %q in report\n%s", want, buf)
+	}
+}
+
+func TestWorkerChecker(t *testing.T) {
+	t.Parallel()
+
+	var agents []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		agents = append(agents, req.UserAgent())
+		if c, err := req.Cookie("session"); err != nil || c.Value != "1" || req.Header.Get("X-Crawl") != "yes" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer s.Close()
+
+	w := newTestWorker()
+	w.Jar = NewJar()
+	w.Jar.SetCookies(mustParseURL(s.URL), []*http.Cookie{{Name: "session", Value: "1"}})
+	w.Header = http.Header{"X-Crawl": {"yes"}}
+	w.UserAgents = []string{"a", "b"}
+	w.Delay = time.Millisecond * 20
+	w.Concurrent = 1
+
+	g := graph.New()
+	g.Add(graph.Edge{Source: s.URL + "/", Target: s.URL + "/a", Kind: "a"})
+	g.Add(graph.Edge{Source: s.URL + "/", Target: s.URL + "/b", Kind: "a"})
+	start := time.Now()
+	if r := w.Checker().Check(g); r.Checked != 2 || len(r.Broken) != 0 {
+		t.Fatalf("checker: unexpected report %+v", r)
+	}
+	if d := time.Since(start); d < w.Delay {
+		t.Fatalf("checker: expected delay of %v, took %v", w.Delay, d)
+	}
+	if len(agents) != 2 || agents[0] != "a" || agents[1] != "b" {
+		t.Fatalf("checker: expected user agents [a b], got %v", agents)
+	}
+}
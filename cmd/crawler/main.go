@@ -0,0 +1,379 @@
+// Command crawler crawls a single host and writes the fetched pages to
+// JSONL, WARC or a local mirror directory.
+//
+// Usage:
+//
+//	crawler [flags] -seed url [-seed url ...]
+//	crawler [flags] -config job.json
+//
+// See package config for the job file format.
+//
+// Exit codes: 0 if every URL was fetched, 1 on errors or if no page was
+// fetched, 2 on usage errors and 3 if some URLs failed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mars9/crawler"
+	"github.com/mars9/crawler/config"
+	"github.com/mars9/crawler/warc"
+)
+
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitUsage   = 2
+	exitPartial = 3
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func run(args []string, stderr io.Writer) int {
+	var seeds, accept, reject, languages, cookies, headers, proxies stringList
+	defaults := config.NewJob()
+
+	flags := flag.NewFlagSet("crawler", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "", "load the crawl job from JSON `file`, other flags override its settings")
+	host := flags.String("host", "", "host `url` to crawl (default host of the first seed)")
+	flags.Var(&seeds, "seed", "seed `url`, may be repeated")
+	sitemap := flags.String("sitemap", "", "sitemap `url` to seed the crawl from")
+	flags.Var(&accept, "accept", "accept URLs matching `regexp`, may be repeated")
+	flags.Var(&reject, "reject", "reject URLs matching `regexp`, may be repeated")
+	flags.Var(&languages, "lang", "only process pages in `language`, such as de, may be repeated")
+	concurrent := flags.Int("concurrent", defaults.Concurrent, "number of concurrent workers")
+	delay := flags.Duration("delay", time.Duration(defaults.Delay), "delay between requests of a worker")
+	maxEnqueue := flags.Int64("max", defaults.MaxEnqueue, "maximum number of enqueued URLs, 0 for no limit")
+	agent := flags.String("agent", defaults.UserAgent, "user-agent string")
+	flags.Var(&cookies, "cookie", "preload `cookies` such as \"consent=yes; lang=de\", may be repeated")
+	cookieJar := flags.String("cookie-jar", "", "load the cookie jar from `file` if it exists and save it after the crawl")
+	flags.Var(&headers, "header", "send `header` such as \"Accept-Language: de\" with every request, may be repeated")
+	referer := flags.Bool("referer", false, "send the page a URL was found on as Referer header")
+	flags.Var(&proxies, "proxy", "send requests through the HTTP, HTTPS or SOCKS5 proxy `url`, may be repeated")
+	proxyPerHost := flags.Bool("proxy-per-host", false, "send all requests to a host through the same proxy instead of rotating proxies")
+	user := flags.String("user", "", "authenticate to the crawled host with HTTP Basic `user:password`")
+	token := flags.String("token", "", "authenticate to the crawled host with bearer `token`")
+	ttl := flags.Duration("ttl", time.Duration(defaults.TTL), "stop the crawl if no URL was queued for `duration`, must be positive")
+	jsonl := flags.String("jsonl", "", "write fetched pages as JSON lines to `file`, - for stdout, compressed if it ends in .gz")
+	jsonlMaxSize := flags.Int64("jsonl-max-size", 0, "rotate the JSON lines file after `bytes`, 0 for no rotation")
+	warcFile := flags.String("warc", "", "write fetched pages to WARC `file`, compressed if it ends in .gz")
+	mirror := flags.String("mirror", "", "mirror fetched pages to `dir`")
+	verbose := flags.Bool("v", false, "log debug messages")
+	quiet := flags.Bool("q", false, "log warnings and errors only")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "crawler: unexpected arguments %q\n", flags.Args())
+		return exitUsage
+	}
+
+	job := defaults
+	if len(*configFile) > 0 {
+		var err error
+		if job, err = config.Load(*configFile); err != nil {
+			fmt.Fprintf(stderr, "crawler: %v\n", err)
+			return exitUsage
+		}
+	}
+	var usage error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			job.Host = *host
+		case "seed":
+			job.Seeds = seeds
+		case "sitemap":
+			job.Sitemap = *sitemap
+		case "accept":
+			job.Accept = accept
+		case "reject":
+			job.Reject = reject
+		case "lang":
+			job.Languages = languages
+		case "concurrent":
+			job.Concurrent = *concurrent
+		case "delay":
+			job.Delay = config.Duration(*delay)
+		case "max":
+			job.MaxEnqueue = *maxEnqueue
+		case "agent":
+			job.UserAgent = *agent
+		case "ttl":
+			job.TTL = config.Duration(*ttl)
+		case "header":
+			job.Headers = make(map[string]string)
+			for _, h := range headers {
+				i := strings.IndexByte(h, ':')
+				if i < 0 {
+					usage = fmt.Errorf("invalid header %q, expected \"Name: value\"", h)
+					continue
+				}
+				job.Headers[strings.TrimSpace(h[:i])] = strings.TrimSpace(h[i+1:])
+			}
+		case "referer":
+			job.Referer = *referer
+		case "proxy", "proxy-per-host":
+			if job.Proxy == nil {
+				job.Proxy = &config.Proxy{}
+			}
+			if f.Name == "proxy" {
+				job.Proxy.URLs = proxies
+			} else {
+				job.Proxy.PerHost = *proxyPerHost
+			}
+		case "cookie":
+			job.Cookies = cookies
+		case "cookie-jar":
+			job.CookieJar = *cookieJar
+		case "jsonl":
+			job.Output.JSONL = *jsonl
+		case "jsonl-max-size":
+			job.Output.JSONLMaxSize = *jsonlMaxSize
+		case "warc":
+			job.Output.WARC = *warcFile
+		case "mirror":
+			job.Output.Mirror = *mirror
+		}
+	})
+	if usage != nil {
+		fmt.Fprintf(stderr, "crawler: %v\n", usage)
+		return exitUsage
+	}
+	if len(*user) > 0 || len(*token) > 0 {
+		setCredentials(job, *user, *token)
+	}
+	w, err := job.Worker()
+	if err != nil {
+		fmt.Fprintf(stderr, "crawler: invalid job:\n%v\n", err)
+		return exitUsage
+	}
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	} else if *quiet {
+		level = slog.LevelWarn
+	}
+	log := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	s := &sinks{log: log}
+	defer s.Close()
+	if err := s.open(job.Output); err != nil {
+		log.Error("open output", "error", err)
+		return exitError
+	}
+
+	w.PageFunc = s.write
+	c := crawler.New(w, time.Duration(job.TTL), log)
+	if err := job.Start(c); err != nil {
+		log.Error("start crawl", "error", err)
+		c.Close()
+		<-c.Done()
+		return exitError
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	select {
+	case <-c.Done():
+	case <-sig:
+		log.Info("interrupted, waiting for workers")
+		c.Close()
+		<-c.Done()
+	}
+	for _, p := range c.Stats().Proxies {
+		if !p.Healthy {
+			log.Warn("unhealthy proxy", "proxy", p.URL, "requests", p.Requests,
+				"failures", p.Failures, "error", p.Error)
+		}
+	}
+
+	if err := job.SaveCookies(w); err != nil {
+		log.Error("save cookies", "error", err)
+		return exitError
+	}
+	if err := s.Close(); err != nil {
+		log.Error("close output", "error", err)
+		return exitError
+	}
+	if s.failed() {
+		return exitError
+	}
+	return exitCode(c.Stats())
+}
+
+// setCredentials sets the basic auth user:password and bearer token
+// credentials of the crawled host of job.
+func setCredentials(job *config.Job, user, token string) {
+	host := job.Host
+	if len(host) == 0 && len(job.Seeds) > 0 {
+		host = job.Seeds[0]
+	} else if len(host) == 0 {
+		host = job.Sitemap
+	}
+	u, err := url.Parse(host)
+	if err != nil || len(u.Host) == 0 {
+		return // reported by job validation
+	}
+
+	if job.Credentials == nil {
+		job.Credentials = make(map[string]config.Credentials)
+	}
+	c := job.Credentials[u.Host]
+	if len(user) > 0 {
+		c.Username, c.Password = user, ""
+		if i := strings.IndexByte(user, ':'); i >= 0 {
+			c.Username, c.Password = user[:i], user[i+1:]
+		}
+	}
+	if len(token) > 0 {
+		c.Token = token
+	}
+	job.Credentials[u.Host] = c
+}
+
+// exitCode returns the exit code of a crawl finished with stats.
+func exitCode(stats crawler.Stats) int {
+	var failed int64
+	for _, n := range stats.Failed {
+		failed += n
+	}
+	switch {
+	case stats.Fetched == 0 && stats.Unchanged == 0:
+		return exitError
+	case failed > 0:
+		return exitPartial
+	}
+	return exitOK
+}
+
+// sinks writes fetched pages to the configured outputs.
+type sinks struct {
+	log *slog.Logger
+
+	mu      sync.Mutex
+	closers []io.Closer
+	jsonl   *crawler.JSONLWriter
+	warc    *warc.Writer
+	mirror  *crawler.Mirror
+	err     error // first write error
+}
+
+func (s *sinks) open(output config.Output) error {
+	if output.JSONL == "-" {
+		s.jsonl = crawler.NewJSONLWriter(os.Stdout, false)
+		s.closers = append(s.closers, s.jsonl)
+	} else if len(output.JSONL) > 0 {
+		jw, err := crawler.NewJSONLFile(output.JSONL, output.JSONLMaxSize)
+		if err != nil {
+			return err
+		}
+		s.jsonl = jw
+		s.closers = append(s.closers, jw)
+	}
+	if len(output.WARC) > 0 {
+		out, err := create(output.WARC)
+		if err != nil {
+			return err
+		}
+		s.closers = append(s.closers, out)
+		s.warc = warc.NewWriter(out)
+		s.warc.Compress = strings.HasSuffix(output.WARC, ".gz")
+		if err = s.warc.Write(warc.NewWarcinfo("crawler", time.Now())); err != nil {
+			return err
+		}
+	}
+	if len(output.Mirror) > 0 {
+		s.mirror = crawler.NewMirror(output.Mirror)
+	}
+	return nil
+}
+
+func create(name string) (io.WriteCloser, error) {
+	if name == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(name)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func (s *sinks) write(page *crawler.Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.jsonl != nil {
+		s.check("jsonl", s.jsonl.Write(page))
+	}
+	if s.warc != nil {
+		r := warc.NewResponse(page.FinalURL.String(), page.Fetched, page.Status, page.Header, page.Data)
+		s.check("warc", s.warc.Write(r))
+	}
+	if s.mirror != nil {
+		s.check("mirror", s.mirror.Save(page.URL, page.Node, page.Data))
+	}
+}
+
+func (s *sinks) check(sink string, err error) {
+	if err != nil {
+		s.log.Error("write output", "sink", sink, "error", err)
+		if s.err == nil {
+			s.err = err
+		}
+	}
+}
+
+func (s *sinks) failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err != nil
+}
+
+// Close converts the links of the mirror and closes the output files.
+// It is safe to call Close more than once.
+func (s *sinks) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.mirror != nil {
+		err = s.mirror.Convert()
+	}
+	for _, c := range s.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	s.closers = nil
+	s.jsonl, s.warc, s.mirror = nil, nil, nil
+	return err
+}
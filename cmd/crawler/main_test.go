@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mars9/crawler"
+)
+
+func startServer(broken bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			link := "/a"
+			if broken {
+				link = "/missing"
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/a">a</a><a href="` + link + `">b</a></body></html>`))
+		case "/a":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/">home</a></body></html>`))
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+}
+
+func TestRun(t *testing.T) {
+	s := startServer(false)
+	defer s.Close()
+
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "pages.jsonl")
+	warcFile := filepath.Join(dir, "pages.warc")
+	mirror := filepath.Join(dir, "mirror")
+
+	stderr := &bytes.Buffer{}
+	code := run([]string{"-seed", s.URL + "/", "-delay", "0", "-ttl", "200ms", "-q",
+		"-jsonl", jsonl, "-warc", warcFile, "-mirror", mirror}, stderr)
+	if code != exitOK {
+		t.Fatalf("run: expected exit code %d, got %d\n%s", exitOK, code, stderr)
+	}
+
+	f, err := os.Open(jsonl)
+	if err != nil {
+		t.Fatalf("run: open jsonl: %v", err)
+	}
+	defer f.Close()
+	urls := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r crawler.PageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("run: decode jsonl: %v", err)
+		}
+		if r.Status != 200 || r.Header.Get("Content-Type") != "text/html" || len(r.Outlinks) == 0 {
+			t.Fatalf("run: unexpected record %+v", r)
+		}
+		urls[r.URL] = true
+	}
+	if len(urls) != 2 || !urls[s.URL+"/"] || !urls[s.URL+"/a"] {
+		t.Fatalf("run: unexpected jsonl urls %v", urls)
+	}
+
+	data, err := ioutil.ReadFile(warcFile)
+	if err != nil {
+		t.Fatalf("run: read warc: %v", err)
+	}
+	if n := strings.Count(string(data), "WARC/1.1\r\n"); n != 3 {
+		t.Fatalf("run: expected 3 warc records, got %d", n)
+	}
+	if !strings.Contains(string(data), "WARC-Target-URI: "+s.URL+"/a\r\n") {
+		t.Fatalf("run: missing warc record of %s/a", s.URL)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(mirror, "*", "index.html"))
+	if len(matches) != 1 {
+		t.Fatalf("run: expected mirrored index.html, got %v", matches)
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	s := startServer(true)
+	defer s.Close()
+
+	stderr := &bytes.Buffer{}
+	if code := run([]string{"-seed", s.URL + "/", "-delay", "0", "-ttl", "200ms", "-q"}, stderr); code != exitPartial {
+		t.Fatalf("run: expected exit code %d, got %d\n%s", exitPartial, code, stderr)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"-seed", "/relative"},
+		{"-seed", s.URL, "extra"},
+		{"-accept", "("},
+		{"-seed", s.URL, "-header", "no colon"},
+		{"-seed", s.URL, "-header", "a b: c"},
+		{"-seed", s.URL, "-proxy", "ftp://proxy:21"},
+		{"-seed", s.URL, "-proxy-per-host"},
+		{"-seed", s.URL, "-ttl", "0"},
+	} {
+		if code := run(args, ioutil.Discard); code != exitUsage {
+			t.Fatalf("run %q: expected exit code %d, got %d", args, exitUsage, code)
+		}
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	s := startServer(false)
+	defer s.Close()
+
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "pages.jsonl")
+	job := `{"seeds": ["` + s.URL + `/"], "delay": "0s", "ttl": "200ms", "output": {"jsonl": "` + jsonl + `"}}`
+	name := filepath.Join(dir, "job.json")
+	if err := ioutil.WriteFile(name, []byte(job), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// -reject overrides the empty reject rules of the job
+	stderr := &bytes.Buffer{}
+	if code := run([]string{"-config", name, "-reject", "/a$", "-q"}, stderr); code != exitOK {
+		t.Fatalf("run: expected exit code %d, got %d\n%s", exitOK, code, stderr)
+	}
+	data, err := ioutil.ReadFile(jsonl)
+	if err != nil {
+		t.Fatalf("run: read jsonl: %v", err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Fatalf("run: expected 1 record, got %d\n%s", n, data)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"-config", name, "-concurrent", "-1"}, stderr); code != exitUsage {
+		t.Fatalf("run: expected exit code %d, got %d", exitUsage, code)
+	}
+	if !strings.Contains(stderr.String(), "concurrent: must not be negative") {
+		t.Fatalf("run: expected validation error, got %q", stderr)
+	}
+}
+
+func TestRunAuth(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if user, pass, ok := req.BasicAuth(); !ok || user != "ann" || pass != "se:cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`<html><body>hello</body></html>`))
+	}))
+	defer s.Close()
+
+	args := []string{"-seed", s.URL + "/", "-delay", "0", "-ttl", "100ms", "-q"}
+	if code := run(append(args, "-user", "ann:se:cret"), ioutil.Discard); code != exitOK {
+		t.Fatalf("run: expected exit code %d, got %d", exitOK, code)
+	}
+	if code := run(args, ioutil.Discard); code != exitError {
+		t.Fatalf("run: expected exit code %d without credentials, got %d", exitError, code)
+	}
+}
+
+func TestRunProxy(t *testing.T) {
+	s := startServer(false)
+	defer s.Close()
+
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		resp, err := http.Get(req.URL.String())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	args := []string{"-seed", s.URL + "/", "-delay", "0", "-ttl", "100ms", "-q", "-proxy", proxy.URL, "-proxy-per-host"}
+	if code := run(args, ioutil.Discard); code != exitOK {
+		t.Fatalf("run: expected exit code %d, got %d", exitOK, code)
+	}
+	if n := atomic.LoadInt32(&proxied); n != 2 {
+		t.Fatalf("run: expected 2 proxied requests, got %d", n)
+	}
+}
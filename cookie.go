@@ -0,0 +1,204 @@
+package crawler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Jar is a cookie jar for Worker.Jar. Cookies are scoped using the public
+// suffix list, so a site cannot set cookies for a whole registry such as
+// co.uk. The jar can be persisted between resumed crawls using WriteTo
+// and ReadFrom.
+type Jar struct {
+	mu  sync.Mutex
+	jar *cookiejar.Jar
+	set map[string]storedCookie // keyed by domain, path and name
+}
+
+// storedCookie is the persisted form of a cookie and the URL that set it.
+type storedCookie struct {
+	URL      string     `json:"url"`
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+	HttpOnly bool       `json:"http_only,omitempty"`
+}
+
+func (c storedCookie) expired(now time.Time) bool {
+	return c.Expires != nil && !c.Expires.After(now)
+}
+
+// NewJar returns an empty Jar.
+func NewJar() *Jar {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		panic(err) // cookiejar.New never fails
+	}
+	return &Jar{jar: jar, set: make(map[string]storedCookie)}
+}
+
+// SetCookies implements http.CookieJar. It can be used to preload
+// cookies, see ParseCookies.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar.SetCookies(u, cookies)
+	now := time.Now()
+	for _, c := range cookies {
+		if !domainMatch(u, c.Domain) {
+			continue // rejected by the jar
+		}
+		s := storedCookie{
+			URL:      u.Scheme + "://" + u.Host + u.EscapedPath(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if c.MaxAge > 0 {
+			t := now.Add(time.Duration(c.MaxAge) * time.Second)
+			s.Expires = &t
+		} else if c.MaxAge < 0 {
+			s.Expires = &now
+		} else if !c.Expires.IsZero() {
+			t := c.Expires
+			s.Expires = &t
+		}
+
+		key := cookieKey(u, c)
+		if s.expired(now) {
+			delete(j.set, key)
+		} else {
+			j.set[key] = s
+		}
+	}
+}
+
+// domainMatch reports whether a cookie set by u may use the domain
+// attribute domain. The domain must not be a public suffix unless it is
+// the host of u.
+func domainMatch(u *url.URL, domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if len(domain) == 0 {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == domain {
+		return true
+	}
+	if !strings.HasSuffix(host, "."+domain) {
+		return false
+	}
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix != domain
+}
+
+// cookieKey returns the key identifying cookie c set by u in the jar.
+func cookieKey(u *url.URL, c *http.Cookie) string {
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	if len(domain) == 0 {
+		domain = strings.ToLower(u.Hostname())
+	}
+	p := c.Path
+	if len(p) == 0 || p[0] != '/' {
+		// default path of RFC 6265 section 5.1.4
+		p = "/"
+		if dir := path.Dir(u.EscapedPath()); strings.HasPrefix(u.EscapedPath(), "/") && dir != "." {
+			p = dir
+		}
+	}
+	return domain + ";" + p + ";" + c.Name
+}
+
+// Cookies implements http.CookieJar.
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Len returns the number of stored cookies, including expired cookies
+// not yet removed.
+func (j *Jar) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.set)
+}
+
+// WriteTo writes the unexpired cookies of the jar as JSON to w. Session
+// cookies are included, so a resumed crawl continues the session.
+func (j *Jar) WriteTo(w io.Writer) (int64, error) {
+	j.mu.Lock()
+	keys := make([]string, 0, len(j.set))
+	for key := range j.set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	now := time.Now()
+	cookies := make([]storedCookie, 0, len(keys))
+	for _, key := range keys {
+		if c := j.set[key]; !c.expired(now) {
+			cookies = append(cookies, c)
+		}
+	}
+	j.mu.Unlock()
+
+	data, err := json.Marshal(cookies)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadFrom reads cookies written by WriteTo from r and adds them to the
+// jar. Expired cookies are skipped.
+func (j *Jar) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countReader{r: r}
+	var cookies []storedCookie
+	if err := json.NewDecoder(cr).Decode(&cookies); err != nil {
+		return cr.n, err
+	}
+
+	now := time.Now()
+	for _, s := range cookies {
+		u, err := url.Parse(s.URL)
+		if err != nil || s.expired(now) {
+			continue
+		}
+		c := &http.Cookie{
+			Name:     s.Name,
+			Value:    s.Value,
+			Domain:   s.Domain,
+			Path:     s.Path,
+			Secure:   s.Secure,
+			HttpOnly: s.HttpOnly,
+		}
+		if s.Expires != nil {
+			c.Expires = *s.Expires
+		}
+		j.SetCookies(u, []*http.Cookie{c})
+	}
+	return cr.n, nil
+}
+
+// ParseCookies parses cookies in the format of a Cookie header, such as
+// "consent=yes; session=abc", for preloading a jar.
+func ParseCookies(s string) []*http.Cookie {
+	req := &http.Request{Header: http.Header{"Cookie": {s}}}
+	return req.Cookies()
+}
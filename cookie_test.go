@@ -0,0 +1,144 @@
+package crawler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func cookieNames(cookies []*http.Cookie) []string {
+	var names []string
+	for _, c := range cookies {
+		names = append(names, c.Name+"="+c.Value)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestJar(t *testing.T) {
+	jar := NewJar()
+	jar.SetCookies(mustParseURL("http://www.example.co.uk/a/b"), []*http.Cookie{
+		{Name: "registry", Value: "1", Domain: "co.uk"},
+		{Name: "site", Value: "1", Domain: ".example.co.uk", Path: "/"},
+		{Name: "host", Value: "1"},
+		{Name: "session", Value: "1", Path: "/", MaxAge: 3600},
+		{Name: "gone", Value: "1", MaxAge: -1},
+		{Name: "old", Value: "1", Expires: time.Now().Add(-time.Hour)},
+	})
+
+	if got := cookieNames(jar.Cookies(mustParseURL("http://shop.example.co.uk/"))); len(got) != 1 || got[0] != "site=1" {
+		t.Fatalf("jar: expected site cookie only, got %v", got)
+	}
+	if got := cookieNames(jar.Cookies(mustParseURL("http://other.co.uk/"))); len(got) != 0 {
+		t.Fatalf("jar: expected no cookies for other site, got %v", got)
+	}
+	want := []string{"host=1", "session=1", "site=1"}
+	if got := cookieNames(jar.Cookies(mustParseURL("http://www.example.co.uk/a/c"))); !reflect.DeepEqual(got, want) {
+		t.Fatalf("jar: expected %v, got %v", want, got)
+	}
+	if n := jar.Len(); n != 3 {
+		t.Fatalf("jar: expected 3 stored cookies, got %d", n)
+	}
+
+	jar.SetCookies(mustParseURL("http://www.example.co.uk/"), []*http.Cookie{{Name: "session", Value: "1", MaxAge: -1}})
+	if n := jar.Len(); n != 2 {
+		t.Fatalf("jar: expected deleted cookie to be removed, got %d cookies", n)
+	}
+}
+
+func TestJarPersist(t *testing.T) {
+	jar := NewJar()
+	jar.SetCookies(mustParseURL("https://www.example.com/shop/cart"), []*http.Cookie{
+		{Name: "session", Value: "abc", Secure: true, HttpOnly: true},
+		{Name: "consent", Value: "yes", Domain: "example.com", Path: "/", MaxAge: 3600},
+	})
+	buf := &bytes.Buffer{}
+	if _, err := jar.WriteTo(buf); err != nil {
+		t.Fatalf("jar: write: %v", err)
+	}
+
+	resumed := NewJar()
+	if _, err := resumed.ReadFrom(buf); err != nil {
+		t.Fatalf("jar: read: %v", err)
+	}
+	for _, test := range []struct {
+		url  string
+		want []string
+	}{
+		{"https://www.example.com/shop/item", []string{"consent=yes", "session=abc"}},
+		{"http://www.example.com/shop/item", []string{"consent=yes"}},
+		{"https://www.example.com/", []string{"consent=yes"}},
+		{"https://static.example.com/", []string{"consent=yes"}},
+	} {
+		got := cookieNames(resumed.Cookies(mustParseURL(test.url)))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("jar: expected cookies %v for %s, got %v", test.want, test.url, got)
+		}
+	}
+}
+
+func TestParseCookies(t *testing.T) {
+	got := cookieNames(ParseCookies("consent=yes; session=abc;in valid=1"))
+	if len(got) != 2 || got[0] != "consent=yes" || got[1] != "session=abc" {
+		t.Fatalf("parse cookies: unexpected cookies %v", got)
+	}
+}
+
+func TestCrawlerCookies(t *testing.T) {
+	t.Parallel()
+
+	// Pages redirect to a consent page until the consent cookie is set,
+	// which redirects back to the requested page.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/consent", func(w http.ResponseWriter, req *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "consent", Value: "yes", Path: "/"})
+		http.Redirect(w, req, req.URL.Query().Get("next"), http.StatusFound)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		if c, err := req.Cookie("consent"); err != nil || c.Value != "yes" {
+			http.Redirect(w, req, "/consent?next="+url.QueryEscape(req.URL.Path), http.StatusFound)
+			return
+		}
+		w.Write([]byte(`<html><body><a href="/a">a</a></body></html>`))
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	crawl := func(jar http.CookieJar) []string {
+		w := newTestWorker()
+		w.GetFunc = nil
+		w.Host = mustParseURL(s.URL + "/")
+		w.Jar = jar
+		var mu sync.Mutex
+		var fetched []string
+		w.PageFunc = func(page *Page) {
+			mu.Lock()
+			fetched = append(fetched, page.URL.Path)
+			mu.Unlock()
+		}
+		c := New(w, time.Millisecond*50, nil)
+		c.Start(nil, w.Host)
+		<-c.Done()
+		sort.Strings(fetched)
+		return fetched
+	}
+
+	if fetched := crawl(nil); len(fetched) != 0 {
+		t.Fatalf("cookies: expected redirect loop without jar, got %v", fetched)
+	}
+	if fetched := crawl(NewJar()); len(fetched) != 2 || fetched[0] != "/" || fetched[1] != "/a" {
+		t.Fatalf("cookies: expected / and /a to be fetched, got %v", fetched)
+	}
+
+	jar := NewJar()
+	jar.SetCookies(mustParseURL(s.URL), ParseCookies("consent=no"))
+	if fetched := crawl(jar); len(fetched) != 2 {
+		t.Fatalf("cookies: expected consent cookie to be replaced, got %v", fetched)
+	}
+}
@@ -1,16 +1,18 @@
 package crawler
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
 	"sync"
 	"time"
 
+	"github.com/mars9/crawler/graph"
 	sm "github.com/mars9/crawler/sitemap"
 	"golang.org/x/net/html"
 )
@@ -22,38 +24,92 @@ const (
 	DefaultDelay       = 3 * time.Second
 )
 
-func init() { log.SetFlags(log.Ldate | log.Lmicroseconds | log.LUTC) }
+// Response is the response body returned by Get. It gives access to
+// the status and headers of the underlying HTTP response.
+type Response struct {
+	*http.Response
+}
+
+func (r *Response) Read(p []byte) (int, error) { return r.Body.Read(p) }
+
+func (r *Response) Close() error { return r.Body.Close() }
+
+// StatusError is returned by Get if the response status is not 200 OK.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string { return e.Status }
+
+// ParseError is returned if a fetched page cannot be parsed.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string { return "parse: " + e.Err.Error() }
 
+// Get issues a GET request to the specified URL. The returned body is a
+// *Response.
 func Get(url *url.URL, agent string, robots Robots) (io.ReadCloser, error) {
+	return get(context.Background(), url, http.Header{"User-Agent": {agent}}, robots, nil, nil)
+}
+
+// get issues a conditional GET request if cache holds validators from a
+// previous fetch of url and returns ErrNotModified if the resource did
+// not change since. The request is sent with header, and DefaultUserAgent
+// if header has no User-Agent. If client is nil http.DefaultClient is
+// used.
+func get(ctx context.Context, url *url.URL, header http.Header, robots Robots, cache Cache, client *http.Client) (io.ReadCloser, error) {
 	if !url.IsAbs() {
-		return nil, errors.New("not an absolute URL")
+		return nil, ErrNotAbsoluteURL
 	}
 	if robots != nil && !robots.Test(url) {
-		return nil, errors.New("rejected by robots.txt")
+		return nil, ErrRobotsRejected
 	}
 
-	req, err := http.NewRequest("GET", url.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	if len(agent) == 0 {
+	for key, values := range header {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	if len(req.Header.Get("User-Agent")) == 0 {
 		req.Header.Set("User-Agent", DefaultUserAgent)
-	} else {
-		req.Header.Set("User-Agent", agent)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		if resp.Body != nil { // discard reader
-			io.Copy(ioutil.Discard, resp.Body)
+	var cached bool
+	if cache != nil {
+		var v Validator
+		if v, cached = cache.Load(url); cached {
+			if len(v.ETag) > 0 {
+				req.Header.Set("If-None-Match", v.ETag)
+			}
+			if len(v.LastModified) > 0 {
+				req.Header.Set("If-Modified-Since", v.LastModified)
+			}
 		}
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode == http.StatusNotModified && cached {
+		io.Copy(ioutil.Discard, resp.Body) // discard reader
+		resp.Body.Close()
+		return nil, ErrNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
 		io.Copy(ioutil.Discard, resp.Body) // discard reader
-		return nil, errors.New(resp.Status)
+		resp.Body.Close()
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status}
 	}
-	return resp.Body, nil
+	return &Response{resp}, nil
 }
 
 func Accept(url *url.URL, host string, reject, accept []*regexp.Regexp) bool {
@@ -83,10 +139,61 @@ func Accept(url *url.URL, host string, reject, accept []*regexp.Regexp) bool {
 	return false
 }
 
+// Page represents a fetched and parsed page.
+type Page struct {
+	URL  *url.URL
+	Node *html.Node
+	Data []byte
+
+	// FinalURL is the URL the page was fetched from after following
+	// redirects.
+	FinalURL *url.URL
+
+	// Status and Header are the response status code and headers.
+	Status int
+	Header http.Header
+
+	// Fetched is the time the page was fetched.
+	Fetched time.Time
+
+	// Depth is the number of links followed from a seed to the page.
+	Depth int
+
+	// Fingerprint identifies the text content of the page.
+	Fingerprint Fingerprint
+
+	// Duplicate is the URL of an already fetched page this page is an
+	// exact or near-duplicate of, or nil.
+	Duplicate *url.URL
+
+	// Record holds the fields extracted by Worker.Extractor, or nil.
+	Record map[string]interface{}
+
+	// Language is the lowercase primary language subtag of the page,
+	// such as "de", or empty if unknown. Alternates maps the hreflang
+	// tags of the page to the URLs of its translations.
+	Language   string
+	Alternates map[string]string
+}
+
+// Extractor extracts a record of named fields from the document node
+// fetched from url. See package extract for selector based rules.
+type Extractor interface {
+	Extract(url *url.URL, node *html.Node) map[string]interface{}
+}
+
 type Robots interface {
 	Test(*url.URL) bool
 }
 
+// AgentRobots is implemented by Robots which select the robots.txt group
+// by user agent. The worker tests URLs with Worker.RobotsAgent, so the
+// matched group does not change when user agents are rotated.
+type AgentRobots interface {
+	Robots
+	TestAgent(url *url.URL, agent string) bool
+}
+
 // Worker represents a crawler worker implementation.
 type Worker struct {
 	// GetFunc issues a GET request to the specified URL and returns the
@@ -99,6 +206,13 @@ type Worker struct {
 	// ProcessFunc can be used to scrape data.
 	ProcessFunc func(*url.URL, *html.Node, []byte)
 
+	// PageFunc is called with every fetched page after ProcessFunc.
+	PageFunc func(*Page)
+
+	// UnchangedFunc is called instead of ProcessFunc for URLs which did
+	// not change since the crawl that filled Cache.
+	UnchangedFunc func(*url.URL)
+
 	// Host defines the hostname to crawl. Worker is a single-host crawler.
 	Host *url.URL
 
@@ -119,19 +233,122 @@ type Worker struct {
 	// number of pages visited will be at least MaxEnqueues, possibly more.
 	MaxEnqueue int64
 
-	// RobotsAgent defines the user-agent string to use for robots.txt.
-	//RobotsAgent string
+	// UserAgents, if set, is a pool of user-agent strings rotated
+	// between requests instead of UserAgent.
+	UserAgents []string
+
+	// Header holds headers sent with every request, such as Accept,
+	// Accept-Language or custom X- headers. HostHeader holds headers sent
+	// to the hosts they are keyed by, overriding Header. Both override
+	// the User-Agent and Referer set by the worker.
+	Header     http.Header
+	HostHeader map[string]http.Header
+
+	// Referer sends the URL of the page a URL was discovered on as
+	// Referer header.
+	Referer bool
+
+	// RobotsAgent defines the user-agent string used to select the
+	// robots.txt group if Robots implements AgentRobots. It defaults to
+	// DefaultRobotsAgent.
+	RobotsAgent string
 
 	Robots Robots
 
+	// Cache stores the ETag and Last-Modified validators of fetched URLs.
+	// If set, URLs are fetched with conditional requests and the links of
+	// unchanged URLs are taken from the cache.
+	Cache Cache
+
+	// Jar holds the cookies of the crawl if set. Cookies set by a
+	// response or redirect are sent with later requests, so consent and
+	// session cookies survive the crawl. See Jar.
+	Jar http.CookieJar
+
+	// Credentials authenticate the requests to the hosts they are keyed
+	// by, such as "staging.example.com:8080". They are applied to every
+	// request, including redirects and the requests of Login.
+	Credentials map[string]*Credentials
+
+	// Login, if set, logs in with a form before the crawl is seeded and
+	// again whenever the session expires. It requires Jar to keep the
+	// session; Start sets a new Jar if Jar is nil.
+	Login *Login
+
+	// Proxies, if set, is the pool of proxies the requests are sent
+	// through, including the requests of Login and the sitemap fetched
+	// by Crawler.Start. Use Worker.Checker to check links through the
+	// pool. See ProxyPool.
+	Proxies *ProxyPool
+
+	// Schedule enables the recrawl mode. If set, fetched URLs are
+	// re-enqueued when they fall due according to Schedule and the
+	// crawl does not terminate on queue TTL but runs until closed. Pages
+	// rejected by language or skipped as duplicates are not recrawled.
+	Schedule *Schedule
+
+	// Duplicates indexes the fingerprints of fetched pages. If set, pages
+	// are checked for exact and near-duplicates of already fetched pages.
+	// If SkipDuplicates is true, links of duplicates are not extracted
+	// and duplicates are not processed.
+	Duplicates     *Duplicates
+	SkipDuplicates bool
+
+	// Graph records the link graph of the crawl if set. Every http and
+	// https link found on a fetched page is added, including links which
+	// are not followed. Nodes are named by URL key, the Key function of
+	// Graph is set accordingly if nil.
+	Graph *graph.Graph
+
+	// Extractor extracts the Record of every fetched page if set.
+	Extractor Extractor
+
+	// Languages restricts the crawl to pages in one of the given
+	// languages, such as "de". Pages detected in another language are
+	// neither processed nor followed, but their hreflang alternates are
+	// enqueued. Pages of unknown language are accepted.
+	Languages []string
+
+	// PriorityFunc computes the crawl priority of an enqueued URL. URLs
+	// with a higher priority are fetched first. See RankPriority.
+	PriorityFunc func(*url.URL) float64
+
 	Concurrent int
+
+	agent uint32 // index of the next user agent of UserAgents
 }
 
 func (w *Worker) Get(url *url.URL) (io.ReadCloser, error) {
+	return w.get(context.Background(), url, "")
+}
+
+// get fetches url discovered on the page referrer, which may be empty.
+func (w *Worker) get(ctx context.Context, url *url.URL, referrer string) (io.ReadCloser, error) {
 	if w.GetFunc != nil {
 		return w.GetFunc(url)
 	}
-	return Get(url, w.UserAgent, w.Robots)
+	return get(ctx, url, w.header(referrer), w.robots(), w.Cache, w.client())
+}
+
+// client returns the HTTP client fetching the URLs of the worker.
+func (w *Worker) client() *http.Client {
+	if w.Jar == nil && len(w.Credentials) == 0 && len(w.HostHeader) == 0 && w.Proxies == nil {
+		return http.DefaultClient
+	}
+	c := &http.Client{Jar: w.Jar}
+	var base http.RoundTripper = http.DefaultTransport
+	if w.Proxies != nil {
+		base = w.Proxies
+		c.Transport = base
+	}
+	if len(w.Credentials) > 0 || len(w.HostHeader) > 0 {
+		c.Transport = &hostTransport{
+			credentials: w.Credentials,
+			header:      w.HostHeader,
+			base:        base,
+		}
+	}
+	return c
 }
 
 func (w *Worker) IsAccepted(url *url.URL) bool {
@@ -147,35 +364,85 @@ func (w *Worker) Process(url *url.URL, node *html.Node, data []byte) {
 	}
 }
 
+func (w *Worker) ProcessPage(page *Page) {
+	if w.PageFunc != nil {
+		w.PageFunc(page)
+	}
+}
+
+func (w *Worker) Unchanged(url *url.URL) {
+	if w.UnchangedFunc != nil {
+		w.UnchangedFunc(url)
+	}
+}
+
 type worker struct {
-	wg     *sync.WaitGroup
-	work   chan *url.URL
-	done   int
-	id     int
-	pusher pusher
-	w      *Worker
-	logger *log.Logger
+	wg      *sync.WaitGroup
+	work    chan *url.URL
+	done    int
+	id      int
+	pusher  pusher
+	w       *Worker
+	logger  *slog.Logger
+	stats   *stats
+	events  *observers
+	origins *origins
+	links   []string // anchor links of the current page
+	status  int      // response status of the current page
+	size    int      // response size of the current page
 
 	limitReached bool
 	closed       bool
 }
 
-func (w *worker) printf(format string, args ...interface{}) {
+func (w *worker) log(level slog.Level, msg string, args ...interface{}) {
 	if w.logger != nil {
-		w.logger.Printf(format, args...)
+		w.logger.Log(context.Background(), level, msg, args...)
 	}
 }
 
+// retry records that the request of url is retried after it failed
+// with err.
+func (w *worker) retry(url *url.URL, err error) {
+	w.log(slog.LevelDebug, "retry request", "url", url.String(), "error", err)
+	w.stats.retried()
+	w.events.emit(Event{Kind: EventRetry, Worker: w.id, URL: url, Reason: err})
+}
+
 func (w *worker) run() {
 	for url := range w.work {
-		w.printf("worker#%.3d received %q", w.id, url)
-		if err := w.fetch(url); err != nil {
-			w.printf("worker#%.3d ERROR %q: %v", w.id, url, err)
+		w.log(slog.LevelDebug, "received url", "url", url.String())
+		w.stats.begin(w.id, url)
+		w.events.emit(Event{Kind: EventFetchStarted, Worker: w.id, URL: url})
+		w.status, w.size = 0, 0
+		start := time.Now()
+		err := w.fetch(url)
+		duration := time.Since(start)
+		if err != nil {
+			w.log(slog.LevelWarn, "fetch failed", "url", url.String(), "error", err,
+				"kind", category(err), "duration", duration)
+		} else {
+			w.log(slog.LevelInfo, "fetched url", "url", url.String(), "status", w.status,
+				"size", w.size, "duration", duration)
+		}
+		w.stats.end(w.id, duration, err)
+		w.events.emit(Event{
+			Kind:     EventFetchFinished,
+			Worker:   w.id,
+			URL:      url,
+			Reason:   err,
+			Status:   w.status,
+			Size:     w.size,
+			Duration: duration,
+		})
+		if err == ErrRobotsRejected {
+			w.events.emit(Event{Kind: EventRobotsBlocked, Worker: w.id, URL: url})
 		}
 		w.done++
 		if w.w.Delay > 0 {
 			time.Sleep(w.w.Delay)
 		}
+		w.events.emit(Event{Kind: EventWorkerIdle, Worker: w.id})
 	}
 	w.closed = true
 	w.wg.Done()
@@ -189,7 +456,17 @@ func (w *worker) fetch(url *url.URL) error {
 		return ErrNotAbsoluteURL
 	}
 
-	body, err := w.w.Get(url)
+	fetched := time.Now()
+	body, err := w.get(url)
+	if err == ErrNotModified {
+		w.status = http.StatusNotModified
+		w.stats.response(w.status, 0)
+		if w.w.Schedule != nil {
+			w.w.Schedule.Unchanged(url)
+		}
+		w.unchanged(url)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -199,6 +476,16 @@ func (w *worker) fetch(url *url.URL) error {
 	if err != nil {
 		return err
 	}
+	w.status, w.size = http.StatusOK, len(data)
+	header, final := http.Header{}, url
+	if resp, ok := body.(*Response); ok {
+		w.status, header = resp.StatusCode, resp.Header
+		if resp.Request != nil && resp.Request.URL.String() != url.String() {
+			final = resp.Request.URL
+			w.events.emit(Event{Kind: EventRedirect, Worker: w.id, URL: url, Target: final})
+		}
+	}
+	w.stats.response(w.status, w.size)
 	//data, _, err = transform.Transform(data, nil)
 	//if err != nil {
 	//	return err
@@ -206,52 +493,133 @@ func (w *worker) fetch(url *url.URL) error {
 
 	node, err := parseHTML(data)
 	if err != nil {
-		return err
+		return &ParseError{err}
+	}
+
+	page := &Page{
+		URL:         url,
+		Node:        node,
+		Data:        data,
+		FinalURL:    final,
+		Status:      w.status,
+		Header:      header,
+		Fetched:     fetched,
+		Depth:       w.origins.get(url).depth,
+		Fingerprint: NewFingerprint(node),
+	}
+	page.Language, page.Alternates = detectLanguage(final, header, node)
+	if !w.w.acceptLanguage(page.Language) {
+		w.log(slog.LevelDebug, "page rejected", "url", url.String(), "language", page.Language)
+		w.events.emit(Event{Kind: EventRejected, Worker: w.id, URL: url, Reason: ErrRejectedLang})
+		w.links = w.links[:0]
+		w.enqueueAlternates(url, page.Alternates)
+		return nil
+	}
+	if w.w.Duplicates != nil {
+		page.Duplicate = w.w.Duplicates.Add(url, page.Fingerprint)
+		if page.Duplicate != nil && w.w.SkipDuplicates {
+			w.log(slog.LevelInfo, "skip duplicate page", "url", url.String(), "duplicate", page.Duplicate.String())
+			return nil
+		}
+	}
+	// rejected and skipped pages are not recrawled
+	if w.w.Schedule != nil {
+		w.w.Schedule.Observe(url, page.Fingerprint.Hash)
 	}
 
+	if w.w.Graph != nil {
+		w.w.Graph.AddNode(urlKey(url), url.String())
+	}
+	if w.w.Extractor != nil {
+		page.Record = w.w.Extractor.Extract(final, node)
+	}
+	w.links = w.links[:0]
 	w.parse(url, node, w.pusher)
 	w.w.Process(url, node, data)
+	w.w.ProcessPage(page)
+	w.stats.processed()
+
+	if resp, ok := body.(*Response); ok && w.w.Cache != nil {
+		w.w.Cache.Store(url, Validator{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Links:        append([]string(nil), w.links...),
+		})
+	}
 	return nil
 }
 
-func (w *worker) parse(parent *url.URL, node *html.Node, pusher pusher) {
-	if w.limitReached || w.closed {
+// unchanged enqueues the cached links of page which are accepted.
+func (w *worker) unchanged(page *url.URL) {
+	if w.w.Cache != nil {
+		if v, found := w.w.Cache.Load(page); found {
+			// the cached links are absolute
+			for _, link := range v.Links {
+				if w.limitReached || w.closed {
+					break
+				}
+				if u, err := url.Parse(link); err == nil {
+					w.enqueue(page, u, w.pusher)
+				}
+			}
+		}
+	}
+	w.w.Unchanged(page)
+}
+
+func (w *worker) enqueue(parent, url *url.URL, pusher pusher) {
+	if !w.w.IsAccepted(url) { // allowed to enqueue
+		w.log(slog.LevelDebug, "url rejected", "url", url.String(), "error", ErrRejectedURL)
+		w.stats.add(&w.stats.rejected)
+		w.events.emit(Event{Kind: EventRejected, Worker: w.id, URL: url, Parent: parent, Reason: ErrRejectedURL})
+		return
+	}
+	w.origins.set(url, parent)
+	err := pusher.Push(url)
+	if err == nil {
+		w.events.emit(Event{Kind: EventEnqueued, Worker: w.id, URL: url, Parent: parent})
 		return
 	}
+	w.events.emit(Event{Kind: EventRejected, Worker: w.id, URL: url, Parent: parent, Reason: err})
 
-	if node.Type == html.ElementNode && node.Data == "a" {
-		for i := range node.Attr {
-			if node.Attr[i].Key == "href" && node.Attr[i].Val != "" {
-				url, err := normalize(parent, node.Attr[i].Val)
-				if err != nil {
-					continue
-				}
+	switch {
+	case err == ErrDuplicateURL:
+		w.stats.add(&w.stats.duplicates)
+		w.log(slog.LevelDebug, "url rejected", "url", url.String(), "error", err)
 
-				if !w.w.IsAccepted(url) { // allowed to enqueue
-					w.printf("worker#%.3d url parser ERROR %q: rejected url", w.id, url)
-					continue
-				}
-				if err := pusher.Push(url); err != nil {
-					switch {
-					case err == ErrDuplicateURL:
-						// nothing
-						w.printf("worker#%.3d url parser ERROR %q: %v", w.id, url, err)
-
-					case err == ErrEmptyURL:
-						// nothing
-						w.printf("worker#%.3d url parser ERROR %q: %v", w.id, url, err)
-
-					case err == ErrLimitReached:
-						w.limitReached = true
-						return
-
-					case err == ErrQueueClosed:
-						w.closed = true
-						return
-
-					default:
-						panic("unknown queue error")
-					}
+	case err == ErrEmptyURL:
+		w.log(slog.LevelDebug, "url rejected", "url", url.String(), "error", err)
+
+	case err == ErrLimitReached:
+		w.limitReached = true
+
+	case err == ErrQueueClosed:
+		w.closed = true
+
+	default:
+		panic("unknown queue error")
+	}
+}
+
+// parse enqueues the links of node and its descendants, adds them to
+// the link graph and records them in w.links. The tree is walked to the
+// end even if enqueueing stopped, so the graph and the cached links are
+// complete.
+func (w *worker) parse(parent *url.URL, node *html.Node, pusher pusher) {
+	stop := w.limitReached || w.closed
+	if stop && w.w.Graph == nil && w.w.Cache == nil {
+		return
+	}
+
+	if attr := linkAttr(node); attr != nil {
+		if url, err := normalize(parent, attr.Val); err == nil {
+			if w.w.Graph != nil && (url.Scheme == "http" || url.Scheme == "https") {
+				w.w.Graph.Add(newEdge(parent, url, node))
+			}
+			if node.Data == "a" {
+				w.links = append(w.links, url.String())
+				if !stop {
+					w.enqueue(parent, url, pusher)
 				}
 			}
 		}
@@ -262,74 +630,184 @@ func (w *worker) parse(parent *url.URL, node *html.Node, pusher pusher) {
 	}
 }
 
+// origins records the link depth and the referring page of enqueued
+// URLs. The origin of a URL is set when the URL is first enqueued. A nil
+// *origins is valid and records nothing.
+type origins struct {
+	mu        sync.Mutex
+	m         map[string]origin // url key to origin
+	referrers bool              // record referring pages
+}
+
+type origin struct {
+	depth    int
+	referrer string
+}
+
+func (o *origins) get(url *url.URL) origin {
+	if o == nil {
+		return origin{}
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.m[urlKey(url)]
+}
+
+// set records the origin of url found on the page parent, or of the seed
+// url if parent is nil.
+func (o *origins) set(url, parent *url.URL) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := urlKey(url)
+	if _, found := o.m[key]; found {
+		return
+	}
+	var v origin
+	if parent != nil {
+		v.depth = o.m[urlKey(parent)].depth + 1
+		if o.referrers {
+			ref := *parent
+			ref.Fragment, ref.User = "", nil
+			v.referrer = ref.String()
+		}
+	}
+	o.m[key] = v
+}
+
 type Crawler struct {
-	wg     *sync.WaitGroup
-	worker []*worker
-	w      *Worker
-	i      int // round-robin index
-	queue  *Queue
-	done   chan struct{}
-	logger *log.Logger
+	wg      *sync.WaitGroup
+	worker  []*worker
+	w       *Worker
+	i       int // round-robin index
+	queue   *Queue
+	stats   *stats
+	events  *observers
+	origins *origins
+	done    chan struct{}
+	logger  *slog.Logger
 }
 
-func New(w *Worker, ttl time.Duration, log *log.Logger) *Crawler {
+// New returns a crawler running w and starts its workers. The crawl
+// terminates if no URL was queued for ttl. If w has a Schedule, ttl is
+// ignored and the crawl runs until closed. If log is nil nothing is
+// logged.
+func New(w *Worker, ttl time.Duration, log *slog.Logger) *Crawler {
 	n := w.Concurrent
 	if n <= 0 {
 		n = 8
 	}
 
+	var queue *Queue
+	if w.Schedule != nil { // recrawl mode
+		queue = NewRecrawlQueue(w.MaxEnqueue)
+	} else {
+		queue = NewQueue(w.MaxEnqueue, ttl)
+	}
+	if w.Graph != nil && w.Graph.Key == nil {
+		w.Graph.Key = graphKey
+	}
+
 	c := &Crawler{
-		queue:  NewQueue(w.MaxEnqueue, ttl),
-		worker: make([]*worker, n),
-		w:      w,
-		wg:     &sync.WaitGroup{},
-		done:   make(chan struct{}),
-		logger: log,
+		queue:   queue,
+		stats:   newStats(n),
+		events:  &observers{},
+		origins: &origins{m: make(map[string]origin), referrers: w.Referer},
+		worker:  make([]*worker, n),
+		w:       w,
+		wg:      &sync.WaitGroup{},
+		done:    make(chan struct{}),
+		logger:  log,
+	}
+	if w.PriorityFunc != nil {
+		c.queue.SetPriority(w.PriorityFunc)
 	}
 
 	for i := 0; i < n; i++ {
 		c.worker[i] = &worker{
-			work:   make(chan *url.URL), // TODO: buffered channel
-			wg:     c.wg,
-			id:     int(i) + 1,
-			pusher: c.queue,
-			w:      w,
-			logger: log,
+			work:    make(chan *url.URL), // TODO: buffered channel
+			wg:      c.wg,
+			id:      int(i) + 1,
+			pusher:  c.queue,
+			stats:   c.stats,
+			events:  c.events,
+			origins: c.origins,
+			w:       w,
+		}
+		if log != nil {
+			c.worker[i].logger = log.With("worker", i+1)
 		}
 		c.wg.Add(1)
 		go c.worker[i].run()
 	}
 
 	go c.run()
+	if w.Schedule != nil {
+		go c.reschedule()
+	}
 	return c
 }
 
-func (c *Crawler) printf(format string, args ...interface{}) {
+func (c *Crawler) log(level slog.Level, msg string, args ...interface{}) {
 	if c.logger != nil {
-		c.logger.Printf(format, args...)
+		c.logger.Log(context.Background(), level, msg, args...)
 	}
 }
 
 func (c *Crawler) Start(sitemap *url.URL, seeds ...*url.URL) error {
+	if c.w.Login != nil {
+		if c.w.Jar == nil {
+			c.w.Jar = NewJar()
+		}
+		if err := c.w.Login.login(c.w, 0); err != nil {
+			return err
+		}
+		c.log(slog.LevelInfo, "logged in", "url", c.w.Login.URL.String())
+	}
 	if sitemap != nil {
-		s, err := sm.Get(sitemap.String(), c.w.UserAgent)
+		// fetched with the client of the worker to use its credentials,
+		// headers and session
+		body, err := get(context.Background(), sitemap, c.w.header(""), nil, nil, c.w.client())
 		if err != nil {
 			return err
 		}
-		for _, seed := range s.URLSet {
-			if err := c.queue.Push(&seed.Loc); err != nil {
-				c.printf("enqueue sitemap %q: %v", seed, err)
+		s, err := sm.Parse(body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+		for i := range s.URLSet {
+			seed := &s.URLSet[i]
+			if c.w.Schedule != nil {
+				c.w.Schedule.Prior(&seed.Loc, seed.ChangeFreq, seed.LastModified)
 			}
+			c.push(&seed.Loc)
 		}
 	}
 	for _, seed := range seeds {
-		if err := c.queue.Push(seed); err != nil {
-			c.printf("enqueue seed %q: %v", seed, err)
-		}
+		c.push(seed)
 	}
 	return nil
 }
 
+func (c *Crawler) push(seed *url.URL) {
+	c.origins.set(seed, nil)
+	if err := c.queue.Push(seed); err != nil {
+		c.log(slog.LevelWarn, "enqueue seed failed", "url", fmt.Sprint(seed), "error", err)
+		c.events.emit(Event{Kind: EventRejected, URL: seed, Reason: err})
+		return
+	}
+	c.events.emit(Event{Kind: EventEnqueued, URL: seed})
+}
+
+// Subscribe registers o to receive the events of the crawl. Observers
+// subscribed after Start may miss the first events.
+func (c *Crawler) Subscribe(o Observer) {
+	c.events.add(o)
+}
+
 func (c *Crawler) dispatch(url *url.URL) {
 	worker := c.worker[c.i]
 	worker.work <- url
@@ -350,14 +828,60 @@ func (c *Crawler) run() {
 
 	var done int
 	for _, w := range c.worker {
-		c.printf("worker#%.3d closed <done:%d closed:%v>", w.id, w.done, w.closed)
+		c.log(slog.LevelDebug, "worker closed", "worker", w.id, "done", w.done, "closed", w.closed)
 		done += w.done
 	}
-	c.printf("crawler visited %d URLs\n", done)
+	c.log(slog.LevelInfo, "crawl finished", "visited", done)
+	c.events.emit(Event{Kind: EventCrawlFinished})
 
 	close(c.done)
 }
 
+// reschedule re-enqueues the URLs of the worker schedule as they fall
+// due until the crawler is closed.
+func (c *Crawler) reschedule() {
+	schedule := c.w.Schedule
+	timer := time.NewTimer(schedule.MinInterval)
+	defer timer.Stop()
+
+	for {
+		for _, url := range schedule.Due(time.Now()) {
+			if err := c.queue.Requeue(url); err != nil {
+				return
+			}
+			c.events.emit(Event{Kind: EventEnqueued, URL: url})
+			c.log(slog.LevelDebug, "recrawl url", "url", url.String())
+		}
+
+		wait := schedule.MinInterval
+		if wait <= 0 {
+			wait = DefaultMinInterval
+		}
+		if next, ok := schedule.Next(); ok {
+			if d := time.Until(next); d < wait {
+				wait = d // overdue URLs are requeued at once
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the crawl statistics. It is safe to call
+// Stats while the crawl is running.
+func (c *Crawler) Stats() Stats {
+	s := c.stats.snapshot()
+	s.Proxies = c.w.Proxies.Stats()
+	s.Queued = c.queue.Enqueued()
+	s.QueueLen = c.queue.Len()
+	return s
+}
+
 func (c *Crawler) Done() <-chan struct{} {
 	return c.done
 }
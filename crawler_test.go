@@ -1,9 +1,12 @@
 package crawler
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -12,6 +15,9 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/mars9/crawler/graph"
+	"golang.org/x/net/html"
 )
 
 func newTestWorker() *Worker {
@@ -201,3 +207,135 @@ func TestGetFunc(t *testing.T) {
 		t.Fatalf("get: expected <nil> body")
 	}
 }
+
+func TestCrawlerLog(t *testing.T) {
+	t.Parallel()
+
+	buf := &safeBuffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	w := newTestWorker()
+	w.GetFunc = func(url *url.URL) (io.ReadCloser, error) {
+		data := `<html><body><a href="/">index</a></body></html>`
+		return ioutil.NopCloser(strings.NewReader(data)), nil
+	}
+	w.Concurrent = 1
+	c := New(w, time.Millisecond*2, logger)
+	c.Start(nil, w.Host)
+	<-c.Done()
+
+	var fetched bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("log: invalid record %q: %v", line, err)
+		}
+		if rec["level"] == "DEBUG" || rec["msg"] == "url rejected" {
+			t.Fatalf("log: unexpected debug record %q", line)
+		}
+		if rec["msg"] == "fetched url" {
+			fetched = true
+			if rec["worker"] != 1.0 || rec["url"] != "http://example.com" || rec["status"] != 200.0 {
+				t.Fatalf("log: unexpected fetch record %q", line)
+			}
+		}
+	}
+	if !fetched {
+		t.Fatalf("log: expected fetch record, got %q", buf.String())
+	}
+}
+
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestCrawlerGraph(t *testing.T) {
+	t.Parallel()
+
+	w := newTestWorker()
+	w.GetFunc = func(u *url.URL) (io.ReadCloser, error) {
+		data := `<html><body>` +
+			`<a href="/a#top" rel="next">Next <b>page</b></a>` +
+			`<a href="http://google.com">Google</a>` +
+			`<a href="mailto:info@example.com">Mail</a>` +
+			`<img src="/logo.png" alt="Logo">` +
+			`</body></html>`
+		if u.Path != "/" && len(u.Path) > 0 {
+			data = `<html><body><a href="/">Home</a></body></html>`
+		}
+		return ioutil.NopCloser(strings.NewReader(data)), nil
+	}
+	w.Graph = graph.New()
+
+	c := New(w, time.Millisecond*20, nil)
+	c.Start(nil, w.Host)
+	<-c.Done()
+
+	want := []graph.Edge{
+		{Source: "http://example.com/", Target: "http://example.com/a", URL: "http://example.com/a", Text: "Next page", Rel: "next", Kind: "a"},
+		{Source: "http://example.com/", Target: "http://google.com/", URL: "http://google.com", Text: "Google", Kind: "a"},
+		{Source: "http://example.com/", Target: "http://example.com/logo.png", URL: "http://example.com/logo.png", Text: "Logo", Kind: "img"},
+		{Source: "http://example.com/a", Target: "http://example.com/", URL: "http://example.com/", Text: "Home", Kind: "a"},
+	}
+	got := w.Graph.Edges()
+	if len(got) != len(want) {
+		t.Fatalf("graph: expected %d edges, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("graph: expected edge %+v, got %+v", want[i], got[i])
+		}
+	}
+	if n := w.Graph.Inlinks("http://example.com/"); n != 1 {
+		t.Fatalf("graph: expected 1 inlink, got %d", n)
+	}
+
+	// sitemap URLs name the nodes of the graph regardless of trailing slashes
+	orphans := w.Graph.Orphans([]string{"http://example.com/a/", "http://example.com/c/"})
+	if len(orphans) != 1 || orphans[0] != "http://example.com/c/" {
+		t.Fatalf("graph: expected orphan http://example.com/c/, got %v", orphans)
+	}
+	if d := w.Graph.Depths("http://example.com"); d["http://example.com/a"] != 1 {
+		t.Fatalf("graph: expected depth 1 of http://example.com/a, got %v", d)
+	}
+}
+
+type titleExtractor struct{}
+
+func (titleExtractor) Extract(u *url.URL, node *html.Node) map[string]interface{} {
+	return map[string]interface{}{"url": u.String(), "title": title(node)}
+}
+
+func TestCrawlerExtractor(t *testing.T) {
+	t.Parallel()
+
+	w := newTestWorker()
+	w.GetFunc = func(u *url.URL) (io.ReadCloser, error) {
+		data := `<html><head><title>Home</title></head><body></body></html>`
+		return ioutil.NopCloser(strings.NewReader(data)), nil
+	}
+	w.Extractor = titleExtractor{}
+	var record map[string]interface{}
+	w.PageFunc = func(page *Page) { record = page.Record }
+
+	c := New(w, time.Millisecond*20, nil)
+	c.Start(nil, w.Host)
+	<-c.Done()
+
+	if record["title"] != "Home" || record["url"] != "http://example.com" {
+		t.Fatalf("extractor: unexpected record %v", record)
+	}
+}
@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// EventKind identifies the type of a crawl Event.
+type EventKind int
+
+const (
+	EventEnqueued      EventKind = iota + 1 // URL pushed to the queue
+	EventRejected                           // URL not enqueued, see Event.Reason
+	EventFetchStarted                       // worker started to fetch URL
+	EventFetchFinished                      // worker finished URL, see Event.Reason
+	EventRedirect                           // URL was redirected to Event.Target
+	EventRobotsBlocked                      // URL rejected by robots.txt
+	EventWorkerIdle                         // worker waits for the next URL
+	EventCrawlFinished                      // all workers are closed
+	EventRetry                              // request of URL retried, see Event.Reason
+)
+
+var eventNames = map[EventKind]string{
+	EventEnqueued:      "enqueued",
+	EventRejected:      "rejected",
+	EventFetchStarted:  "fetch started",
+	EventFetchFinished: "fetch finished",
+	EventRedirect:      "redirect",
+	EventRobotsBlocked: "robots blocked",
+	EventWorkerIdle:    "worker idle",
+	EventCrawlFinished: "crawl finished",
+	EventRetry:         "retry",
+}
+
+func (k EventKind) String() string {
+	if name, found := eventNames[k]; found {
+		return name
+	}
+	return "unknown"
+}
+
+// Event describes a step in the lifecycle of a crawl. Fields not
+// applying to an event kind are left zero.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// Worker is the id of the worker emitting the event, or zero for
+	// events emitted by the crawler.
+	Worker int
+
+	URL    *url.URL
+	Parent *url.URL // page URL was found on
+	Target *url.URL // redirect target
+
+	// Reason holds the rejection reason of EventRejected, the fetch
+	// error of EventFetchFinished and the cause of EventRetry, such as
+	// ErrSessionExpired or the error of a failed proxy.
+	Reason error
+
+	Status   int           // response status
+	Size     int           // response size
+	Duration time.Duration // fetch duration
+}
+
+// Observer receives the events of a crawl. Observe is called
+// synchronously from the crawler goroutines and must not block.
+type Observer interface {
+	Observe(Event)
+}
+
+// ObserverFunc is an adapter to use ordinary functions as observers.
+type ObserverFunc func(Event)
+
+func (fn ObserverFunc) Observe(e Event) { fn(e) }
+
+// observers dispatches events to a list of observers. All methods are
+// safe to call on a nil *observers.
+type observers struct {
+	mu   sync.RWMutex
+	list []Observer
+}
+
+func (o *observers) add(obs Observer) {
+	o.mu.Lock()
+	o.list = append(o.list, obs)
+	o.mu.Unlock()
+}
+
+func (o *observers) emit(e Event) {
+	if o == nil {
+		return
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if len(o.list) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for _, obs := range o.list {
+		obs.Observe(e)
+	}
+}
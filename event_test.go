@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testRobots string
+
+func (r testRobots) Test(u *url.URL) bool { return u.Path != string(r) }
+
+func TestCrawlerEvents(t *testing.T) {
+	t.Parallel()
+
+	pages := map[string]string{
+		"/":        `<html><body><a href="/old">a</a><a href="/private">b</a><a href="http://google.com">c</a><a href="/">d</a></body></html>`,
+		"/new":     `<html><body></body></html>`,
+		"/private": `<html><body></body></html>`,
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/old" {
+			http.Redirect(w, req, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte(pages[req.URL.Path]))
+	}))
+	defer s.Close()
+
+	w := &Worker{Robots: testRobots("/private"), Concurrent: 2}
+	w.Host, _ = url.Parse(s.URL + "/")
+
+	mu := &sync.Mutex{}
+	events := map[EventKind][]Event{}
+	c := New(w, time.Millisecond*20, nil)
+	c.Subscribe(ObserverFunc(func(e Event) {
+		mu.Lock()
+		events[e.Kind] = append(events[e.Kind], e)
+		mu.Unlock()
+	}))
+	c.Start(nil, w.Host)
+	<-c.Done()
+
+	for kind, want := range map[EventKind]int{
+		EventEnqueued:      3,
+		EventRejected:      2,
+		EventFetchStarted:  3,
+		EventFetchFinished: 3,
+		EventRedirect:      1,
+		EventRobotsBlocked: 1,
+		EventWorkerIdle:    3,
+		EventCrawlFinished: 1,
+	} {
+		if got := len(events[kind]); got != want {
+			t.Errorf("events: expected %d %q events, got %d", want, kind, got)
+		}
+	}
+
+	for _, e := range events[EventRejected] {
+		if e.Parent == nil || e.Parent.String() != w.Host.String() {
+			t.Errorf("events: expected parent %q, got %v", w.Host, e.Parent)
+		}
+		if e.Reason != ErrRejectedURL && e.Reason != ErrDuplicateURL {
+			t.Errorf("events: unexpected rejection reason %v", e.Reason)
+		}
+	}
+	if e := events[EventRedirect]; len(e) == 1 && e[0].Target.Path != "/new" {
+		t.Errorf("events: expected redirect to /new, got %q", e[0].Target)
+	}
+	for _, e := range events[EventFetchFinished] {
+		if e.URL.Path == "/private" && e.Reason != ErrRobotsRejected {
+			t.Errorf("events: expected %v, got %v", ErrRobotsRejected, e.Reason)
+		}
+		if e.Worker == 0 || e.Time.IsZero() {
+			t.Errorf("events: expected worker and time, got %+v", e)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Package extract implements declarative scraping rules extracting
+// records of named fields from HTML documents using CSS selectors or
+// XPath 1.0 expressions.
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Field describes a named field of a record.
+type Field struct {
+	Name string `json:"name"`
+
+	// Selector is a CSS selector matched against the descendants of the
+	// document or, for nested fields, of the parent match. If empty the
+	// parent itself is selected.
+	Selector string `json:"selector,omitempty"`
+
+	// XPath is an XPath 1.0 expression used instead of Selector, with the
+	// document or the parent match as context node. Selected attributes
+	// and results of string, number and boolean expressions are
+	// extracted as values.
+	XPath string `json:"xpath,omitempty"`
+
+	// Attr names the attribute to extract. If empty the text content of
+	// the matched element is extracted with whitespace collapsed.
+	Attr string `json:"attr,omitempty"`
+
+	// HTML extracts the rendered HTML of the matched element instead of
+	// its text.
+	HTML bool `json:"html,omitempty"`
+
+	// URL resolves the extracted value as a reference against the URL of
+	// the document.
+	URL bool `json:"url,omitempty"`
+
+	// List extracts the values of all matched elements as a list instead
+	// of the value of the first match.
+	List bool `json:"list,omitempty"`
+
+	// Fields extracts a nested record from every matched element instead
+	// of a single value.
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// Rules is a compiled set of fields.
+type Rules struct {
+	fields []*rule
+}
+
+type rule struct {
+	Field
+	sel    cascadia.Matcher // nil selects the parent
+	xpath  *XPath
+	fields []*rule
+}
+
+// Compile compiles the fields of a record. Field names must be unique
+// and non-empty at every nesting level.
+func Compile(fields []Field) (*Rules, error) {
+	rules, err := compile("", fields)
+	if err != nil {
+		return nil, err
+	}
+	return &Rules{fields: rules}, nil
+}
+
+// MustCompile is like Compile but panics if the fields cannot be
+// compiled.
+func MustCompile(fields []Field) *Rules {
+	r, err := Compile(fields)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func compile(prefix string, fields []Field) ([]*rule, error) {
+	rules := make([]*rule, 0, len(fields))
+	names := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		name := prefix + f.Name
+		if len(f.Name) == 0 {
+			return nil, fmt.Errorf("extract: field %q: empty field name", prefix)
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("extract: field %q: duplicate field name", name)
+		}
+		names[f.Name] = true
+
+		r := &rule{Field: f}
+		if len(f.Selector) > 0 && len(f.XPath) > 0 {
+			return nil, fmt.Errorf("extract: field %q: both selector and xpath given", name)
+		}
+		if len(f.XPath) > 0 {
+			x, err := CompileXPath(f.XPath)
+			if err != nil {
+				return nil, fmt.Errorf("extract: field %q: invalid xpath %q: %v", name, f.XPath, err)
+			}
+			r.xpath = x
+		}
+		if len(f.Selector) > 0 {
+			sel, err := cascadia.ParseGroup(f.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("extract: field %q: invalid selector %q: %v", name, f.Selector, err)
+			}
+			r.sel = sel
+		}
+		if len(f.Fields) > 0 {
+			if len(f.Attr) > 0 || f.HTML || f.URL {
+				return nil, fmt.Errorf("extract: field %q: nested fields cannot have attr, html or url", name)
+			}
+			var err error
+			if r.fields, err = compile(name+".", f.Fields); err != nil {
+				return nil, err
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Extract extracts a record from node, the document fetched from base.
+// Single valued fields without a match are omitted, list fields without
+// a match are empty lists.
+func (r *Rules) Extract(base *url.URL, node *html.Node) map[string]interface{} {
+	return extract(base, node, r.fields)
+}
+
+func extract(base *url.URL, node *html.Node, rules []*rule) map[string]interface{} {
+	record := make(map[string]interface{}, len(rules))
+	for _, r := range rules {
+		matches := r.match(node)
+		if !r.List {
+			if len(matches) > 0 {
+				if v, ok := r.value(base, matches[0]); ok {
+					record[r.Name] = v
+				}
+			}
+			continue
+		}
+		values := make([]interface{}, 0, len(matches))
+		for _, m := range matches {
+			if v, ok := r.value(base, m); ok {
+				values = append(values, v)
+			}
+		}
+		record[r.Name] = values
+	}
+	return record
+}
+
+// match is a node, attribute or XPath result matched by a rule.
+type match struct {
+	node   *html.Node
+	attr   *html.Attribute // matched attribute of node, or nil
+	scalar *string         // formatted non node-set XPath result, or nil
+}
+
+// match returns the matches of the rule in node. Unless the rule is a
+// list rule only the first match is returned.
+func (r *rule) match(node *html.Node) []match {
+	switch {
+	case r.xpath != nil:
+		var matches []match
+		switch v := r.xpath.Evaluate(node).(type) {
+		case []*XPathNode:
+			for _, n := range v {
+				matches = append(matches, match{node: n.Node, attr: n.Attr})
+				if !r.List {
+					break
+				}
+			}
+		default:
+			s := format(v)
+			matches = append(matches, match{node: node, scalar: &s})
+		}
+		return matches
+	case r.sel == nil:
+		return []match{{node: node}}
+	case r.List:
+		var matches []match
+		for _, n := range cascadia.QueryAll(node, r.sel) {
+			matches = append(matches, match{node: n})
+		}
+		return matches
+	}
+	if n := cascadia.Query(node, r.sel); n != nil {
+		return []match{{node: n}}
+	}
+	return nil
+}
+
+// value returns the value of the rule for the match m. It returns false
+// if m has no value, for example if the matched node does not have the
+// extracted attribute.
+func (r *rule) value(base *url.URL, m match) (interface{}, bool) {
+	if m.scalar != nil || m.attr != nil {
+		if len(r.fields) > 0 {
+			return nil, false
+		}
+		if m.attr != nil {
+			return r.resolve(base, strings.TrimSpace(m.attr.Val))
+		}
+		return r.resolve(base, *m.scalar)
+	}
+
+	n := m.node
+	if len(r.fields) > 0 {
+		return extract(base, n, r.fields), true
+	}
+
+	var v string
+	switch {
+	case r.HTML:
+		buf := &bytes.Buffer{}
+		html.Render(buf, n)
+		v = buf.String()
+	case len(r.Attr) > 0:
+		found := false
+		for _, a := range n.Attr {
+			if a.Key == r.Attr {
+				v, found = strings.TrimSpace(a.Val), true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	default:
+		v = Text(n)
+	}
+	return r.resolve(base, v)
+}
+
+// resolve resolves v against base if the rule extracts URLs.
+func (r *rule) resolve(base *url.URL, v string) (interface{}, bool) {
+	if r.URL && base != nil {
+		u, err := base.Parse(v)
+		if err != nil {
+			return nil, false
+		}
+		v = u.String()
+	}
+	return v, true
+}
+
+// Text returns the text content of node with whitespace collapsed,
+// skipping scripts and styles.
+func Text(node *html.Node) string {
+	buf := &bytes.Buffer{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(n.Data)
+			buf.WriteByte(' ')
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript", "template":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
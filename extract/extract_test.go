@@ -0,0 +1,82 @@
+package extract
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const product = `<html><body>
+<h1 class="name">  Blue
+	Widget </h1>
+<img class="photo" src="img/widget.png">
+<ul>
+	<li class="tag">blue</li>
+	<li class="tag">widget</li>
+</ul>
+<div class="review"><span class="author">ann</span><span class="rating">5</span><a href="/r/1">more</a></div>
+<div class="review"><span class="author">bob</span><a href="/r/2">more</a></div>
+<p class="price">9.99 <b>EUR</b></p>
+</body></html>`
+
+func parse(t *testing.T, s string) *html.Node {
+	node, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
+
+func TestExtract(t *testing.T) {
+	rules := MustCompile([]Field{
+		{Name: "name", Selector: "h1.name"},
+		{Name: "photo", Selector: "img.photo", Attr: "src", URL: true},
+		{Name: "tags", Selector: "li.tag", List: true},
+		{Name: "colors", Selector: "li.color", List: true},
+		{Name: "missing", Selector: "h2"},
+		{Name: "price", Selector: ".price", HTML: true},
+		{Name: "reviews", Selector: ".review", List: true, Fields: []Field{
+			{Name: "author", Selector: ".author"},
+			{Name: "rating", Selector: ".rating"},
+			{Name: "link", Selector: "a", Attr: "href", URL: true},
+		}},
+	})
+	base, _ := url.Parse("http://example.com/shop/widget")
+	record := rules.Extract(base, parse(t, product))
+
+	want := map[string]interface{}{
+		"name":   "Blue Widget",
+		"photo":  "http://example.com/shop/img/widget.png",
+		"tags":   []interface{}{"blue", "widget"},
+		"colors": []interface{}{},
+		"price":  `<p class="price">9.99 <b>EUR</b></p>`,
+		"reviews": []interface{}{
+			map[string]interface{}{"author": "ann", "rating": "5", "link": "http://example.com/r/1"},
+			map[string]interface{}{"author": "bob", "link": "http://example.com/r/2"},
+		},
+	}
+	if !reflect.DeepEqual(record, want) {
+		t.Fatalf("extract: expected\n%v\ngot\n%v", want, record)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, test := range []struct {
+		fields []Field
+		err    string
+	}{
+		{[]Field{{Selector: "h1"}}, "empty field name"},
+		{[]Field{{Name: "a", Selector: "h1"}, {Name: "a", Selector: "h2"}}, `field "a": duplicate field name`},
+		{[]Field{{Name: "a", Selector: "h1["}}, `field "a": invalid selector "h1["`},
+		{[]Field{{Name: "a", Fields: []Field{{Name: "b", Selector: ":bogus"}}}}, `field "a.b": invalid selector`},
+		{[]Field{{Name: "a", Attr: "href", Fields: []Field{{Name: "b"}}}}, `field "a": nested fields cannot`},
+	} {
+		_, err := Compile(test.fields)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("compile %+v: expected error %q, got %v", test.fields, test.err, err)
+		}
+	}
+}
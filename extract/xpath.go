@@ -0,0 +1,273 @@
+package extract
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// XPath is a compiled XPath 1.0 expression evaluated over HTML document
+// trees. Absolute location paths start at the document root of the
+// context node.
+type XPath struct {
+	expr *xpath.Expr
+}
+
+// CompileXPath compiles an XPath 1.0 expression.
+func CompileXPath(expr string) (*XPath, error) {
+	e, err := xpath.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &XPath{expr: e}, nil
+}
+
+// MustCompileXPath is like CompileXPath but panics if the expression
+// cannot be compiled.
+func MustCompileXPath(expr string) *XPath {
+	x, err := CompileXPath(expr)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+func (x *XPath) String() string { return x.expr.String() }
+
+// Evaluate evaluates x with node as context node. The result is a
+// float64, string or bool for expressions of these types, and a
+// []*XPathNode for node-set expressions.
+func (x *XPath) Evaluate(node *html.Node) interface{} {
+	switch v := x.expr.Evaluate(newNavigator(node)).(type) {
+	case *xpath.NodeIterator:
+		return collect(v)
+	default:
+		return v
+	}
+}
+
+// Select returns the nodes selected by x with node as context node in
+// document order. Attribute nodes are omitted, see Values.
+func (x *XPath) Select(node *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for _, n := range collect(x.expr.Select(newNavigator(node))) {
+		if n.Attr == nil {
+			nodes = append(nodes, n.Node)
+		}
+	}
+	return nodes
+}
+
+// Values returns the string values of the nodes selected by x with node
+// as context node, or the single formatted result of expressions which
+// do not select nodes.
+func (x *XPath) Values(node *html.Node) []string {
+	switch v := x.Evaluate(node).(type) {
+	case []*XPathNode:
+		values := make([]string, len(v))
+		for i, n := range v {
+			values[i] = n.Value()
+		}
+		return values
+	default:
+		return []string{format(v)}
+	}
+}
+
+// QueryXPath returns the nodes selected by the XPath expression expr
+// with node as context node, see XPath.Select.
+func QueryXPath(node *html.Node, expr string) ([]*html.Node, error) {
+	x, err := CompileXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return x.Select(node), nil
+}
+
+// XPathNode is a node selected by an XPath expression. For attribute
+// nodes Attr is set and Node is the element owning the attribute.
+type XPathNode struct {
+	Node *html.Node
+	Attr *html.Attribute
+}
+
+// Value returns the XPath string value of n.
+func (n *XPathNode) Value() string {
+	if n.Attr != nil {
+		return n.Attr.Val
+	}
+	return stringValue(n.Node)
+}
+
+func collect(it *xpath.NodeIterator) []*XPathNode {
+	var nodes []*XPathNode
+	for it.MoveNext() {
+		nav := it.Current().(*navigator)
+		n := &XPathNode{Node: nav.curr}
+		if nav.attr >= 0 {
+			n.Attr = &nav.curr.Attr[nav.attr]
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// stringValue returns the concatenated text of node and its descendants.
+func stringValue(node *html.Node) string {
+	switch node.Type {
+	case html.TextNode, html.CommentNode:
+		return node.Data
+	}
+	buf := &bytes.Buffer{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return buf.String()
+}
+
+// navigator implements xpath.NodeNavigator over a html.Node tree.
+type navigator struct {
+	root *html.Node
+	curr *html.Node
+	attr int // index of the current attribute of curr, -1 if none
+}
+
+func newNavigator(node *html.Node) *navigator {
+	root := node
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return &navigator{root: root, curr: node, attr: -1}
+}
+
+func (nav *navigator) NodeType() xpath.NodeType {
+	if nav.attr >= 0 {
+		return xpath.AttributeNode
+	}
+	switch nav.curr.Type {
+	case html.DocumentNode:
+		return xpath.RootNode
+	case html.ElementNode:
+		return xpath.ElementNode
+	case html.TextNode:
+		return xpath.TextNode
+	}
+	return xpath.CommentNode // comments and doctypes
+}
+
+func (nav *navigator) LocalName() string {
+	if nav.attr >= 0 {
+		return nav.curr.Attr[nav.attr].Key
+	}
+	if nav.curr.Type == html.ElementNode {
+		return nav.curr.Data
+	}
+	return ""
+}
+
+func (nav *navigator) Prefix() string {
+	if nav.attr >= 0 {
+		return nav.curr.Attr[nav.attr].Namespace
+	}
+	return ""
+}
+
+func (nav *navigator) Value() string {
+	if nav.attr >= 0 {
+		return nav.curr.Attr[nav.attr].Val
+	}
+	return stringValue(nav.curr)
+}
+
+func (nav *navigator) Copy() xpath.NodeNavigator {
+	n := *nav
+	return &n
+}
+
+func (nav *navigator) MoveToRoot() {
+	nav.curr, nav.attr = nav.root, -1
+}
+
+func (nav *navigator) MoveToParent() bool {
+	if nav.attr >= 0 {
+		nav.attr = -1
+		return true
+	}
+	if nav.curr.Parent == nil {
+		return false
+	}
+	nav.curr = nav.curr.Parent
+	return true
+}
+
+func (nav *navigator) MoveToNextAttribute() bool {
+	if nav.attr+1 >= len(nav.curr.Attr) {
+		return false
+	}
+	nav.attr++
+	return true
+}
+
+func (nav *navigator) MoveToChild() bool {
+	if nav.attr >= 0 || nav.curr.FirstChild == nil {
+		return false
+	}
+	nav.curr = nav.curr.FirstChild
+	return true
+}
+
+func (nav *navigator) MoveToFirst() bool {
+	if nav.attr >= 0 || nav.curr.PrevSibling == nil {
+		return false
+	}
+	for nav.curr.PrevSibling != nil {
+		nav.curr = nav.curr.PrevSibling
+	}
+	return true
+}
+
+func (nav *navigator) MoveToNext() bool {
+	if nav.attr >= 0 || nav.curr.NextSibling == nil {
+		return false
+	}
+	nav.curr = nav.curr.NextSibling
+	return true
+}
+
+func (nav *navigator) MoveToPrevious() bool {
+	if nav.attr >= 0 || nav.curr.PrevSibling == nil {
+		return false
+	}
+	nav.curr = nav.curr.PrevSibling
+	return true
+}
+
+func (nav *navigator) MoveTo(other xpath.NodeNavigator) bool {
+	n, ok := other.(*navigator)
+	if !ok || n.root != nav.root {
+		return false
+	}
+	nav.curr, nav.attr = n.curr, n.attr
+	return true
+}
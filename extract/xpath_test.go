@@ -0,0 +1,105 @@
+package extract
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const article = `<html><head><title>News</title></head><body>
+<div id="main">
+	<h1 lang="en">First  post</h1>
+	<p class="meta">by <a href="/u/ann">ann</a></p>
+	<ul>
+		<li>one</li>
+		<li class="x">two</li>
+		<li>three</li>
+	</ul>
+</div>
+<!-- footer -->
+<p id="footer">(c) 2016</p>
+</body></html>`
+
+func TestXPath(t *testing.T) {
+	doc := parse(t, article)
+
+	for _, test := range []struct {
+		expr string
+		want []string
+	}{
+		{"//h1", []string{"First  post"}},
+		{"//li[2]", []string{"two"}},
+		{"//li[last()]", []string{"three"}},
+		{"//li[@class='x']/following-sibling::li", []string{"three"}},
+		{"//li[@class='x']/preceding-sibling::li", []string{"one"}},
+		{"//a/ancestor::div/@id", []string{"main"}},
+		{"//a/@href", []string{"/u/ann"}},
+		{"//h1/@lang | //p/@id", []string{"en", "footer"}},
+		{"//li[contains(., 'o')]", []string{"one", "two"}},
+		{"//li[starts-with(text(), 't')]", []string{"two", "three"}},
+		{"//body/comment()", []string{" footer "}},
+		{"normalize-space(//h1)", []string{"First post"}},
+		{"substring-after(//p[@id='footer'], '(c) ')", []string{"2016"}},
+		{"concat(//title, ': ', //a)", []string{"News: ann"}},
+		{"count(//li)", []string{"3"}},
+		{"string-length(//title) > 3", []string{"true"}},
+	} {
+		x, err := CompileXPath(test.expr)
+		if err != nil {
+			t.Fatalf("xpath %q: %v", test.expr, err)
+		}
+		if got := x.Values(doc); !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("xpath %q: expected %q, got %q", test.expr, test.want, got)
+		}
+	}
+
+	// relative paths and the context node
+	ul, err := QueryXPath(doc, "//ul")
+	if err != nil || len(ul) != 1 {
+		t.Fatalf("xpath: expected ul, got %v, %v", ul, err)
+	}
+	items := MustCompileXPath("li").Select(ul[0])
+	if len(items) != 3 || items[0].FirstChild.Data != "one" {
+		t.Fatalf("xpath: expected 3 relative items, got %d", len(items))
+	}
+	if got := MustCompileXPath("count(//p)").Evaluate(ul[0]); got != float64(2) {
+		t.Fatalf("xpath: expected absolute path from document root, got %v", got)
+	}
+	if nodes := MustCompileXPath("//a/@href").Select(doc); len(nodes) != 0 {
+		t.Fatalf("xpath: expected attributes to be omitted, got %v", nodes)
+	}
+
+	if _, err := CompileXPath("//li["); err == nil {
+		t.Fatalf("xpath: expected compile error")
+	}
+}
+
+func TestExtractXPath(t *testing.T) {
+	rules := MustCompile([]Field{
+		{Name: "title", XPath: "normalize-space(//h1)"},
+		{Name: "author", XPath: "//p[@class='meta']/a/@href", URL: true},
+		{Name: "items", XPath: "//li", List: true},
+		{Name: "count", XPath: "count(//li)"},
+		{Name: "main", XPath: "//div[@id='main']", Fields: []Field{
+			{Name: "first", XPath: ".//li[1]"},
+			{Name: "lang", Selector: "h1", Attr: "lang"},
+		}},
+	})
+	base, _ := url.Parse("http://example.com/news/1")
+	record := rules.Extract(base, parse(t, article))
+
+	want := map[string]interface{}{
+		"title":  "First post",
+		"author": "http://example.com/u/ann",
+		"items":  []interface{}{"one", "two", "three"},
+		"count":  "3",
+		"main":   map[string]interface{}{"first": "one", "lang": "en"},
+	}
+	if !reflect.DeepEqual(record, want) {
+		t.Fatalf("extract: expected\n%v\ngot\n%v", want, record)
+	}
+
+	if _, err := Compile([]Field{{Name: "a", Selector: "h1", XPath: "//h1"}}); err == nil {
+		t.Fatalf("compile: expected error for selector and xpath")
+	}
+}
@@ -0,0 +1,147 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	DefaultDistance = 3 // maximum SimHash distance of near-duplicates
+	shingleSize     = 3 // number of words per SimHash feature
+)
+
+// Fingerprint identifies the text content of a page. The zero value
+// is the fingerprint of a page without text.
+type Fingerprint struct {
+	// Hash is the exact hash of the words of the page text.
+	Hash uint64
+
+	// SimHash is a locality sensitive hash over word shingles of the
+	// page text. Similar texts have SimHashes differing in few bits.
+	SimHash uint64
+}
+
+// NewFingerprint returns the fingerprint of the text content of node.
+func NewFingerprint(node *html.Node) Fingerprint {
+	words := strings.Fields(strings.ToLower(text(node)))
+	if len(words) == 0 {
+		return Fingerprint{}
+	}
+
+	exact := fnv.New64a()
+	for _, word := range words {
+		exact.Write([]byte(word))
+		exact.Write([]byte{' '})
+	}
+
+	var weights [64]int
+	n := len(words) - shingleSize + 1
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		feature := h.Sum64()
+		for bit := uint(0); bit < 64; bit++ {
+			if feature&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var simhash uint64
+	for bit := uint(0); bit < 64; bit++ {
+		if weights[bit] > 0 {
+			simhash |= 1 << bit
+		}
+	}
+	return Fingerprint{Hash: exact.Sum64(), SimHash: simhash}
+}
+
+// Distance returns the number of differing SimHash bits of f and g.
+func (f Fingerprint) Distance(g Fingerprint) int {
+	return bits.OnesCount64(f.SimHash ^ g.SimHash)
+}
+
+// Duplicates is an index of page fingerprints used to detect exact and
+// near-duplicate pages, such as printable versions or pages differing
+// only in session IDs.
+type Duplicates struct {
+	// Distance defines the maximum number of differing SimHash bits of
+	// near-duplicate pages. The index only guarantees to find
+	// near-duplicates for distances lower than 4.
+	Distance int
+
+	mu    sync.Mutex
+	exact map[uint64]*url.URL
+	bands [4]map[uint16][]fingerprintEntry
+}
+
+type fingerprintEntry struct {
+	url *url.URL
+	fp  Fingerprint
+}
+
+// NewDuplicates returns an empty index detecting near-duplicates within
+// DefaultDistance.
+func NewDuplicates() *Duplicates {
+	return &Duplicates{Distance: DefaultDistance}
+}
+
+// Add adds the fingerprint f of the page to the index. If the
+// index already holds an exact or near-duplicate of another page, the
+// URL of that page is returned and f is not added.
+func (d *Duplicates) Add(page *url.URL, f Fingerprint) *url.URL {
+	if f == (Fingerprint{}) { // no text
+		return nil
+	}
+	key := urlKey(page)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.exact == nil {
+		d.exact = make(map[uint64]*url.URL)
+		for i := range d.bands {
+			d.bands[i] = make(map[uint16][]fingerprintEntry)
+		}
+	}
+
+	if dup, found := d.exact[f.Hash]; found {
+		if urlKey(dup) == key {
+			return nil
+		}
+		return dup
+	}
+	// Two SimHashes within a distance lower than 4 share at least one
+	// of their four 16 bit bands.
+	for i := range d.bands {
+		for _, e := range d.bands[i][band(f.SimHash, i)] {
+			if f.Distance(e.fp) <= d.Distance && urlKey(e.url) != key {
+				return e.url
+			}
+		}
+	}
+
+	d.exact[f.Hash] = page
+	for i := range d.bands {
+		b := band(f.SimHash, i)
+		d.bands[i][b] = append(d.bands[i][b], fingerprintEntry{page, f})
+	}
+	return nil
+}
+
+func band(simhash uint64, i int) uint16 {
+	return uint16(simhash >> (uint(i) * 16))
+}
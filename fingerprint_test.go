@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const article = `Package crawler provides a load-balanced, concurrent and
+flexible crawler that follows the robots.txt policies and crawl delays in
+its default configuration. This software is new, experimental, and under
+heavy development. The documentation is lacking, if any. There are almost
+no tests. You have been warned.
+
+Permission to use, copy, modify, and distribute this software for any
+purpose with or without fee is hereby granted, provided that the above
+copyright notice and this permission notice appear in all copies.
+
+THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.`
+
+func mustFingerprint(t *testing.T, data string) Fingerprint {
+	node, err := parseHTML([]byte(data))
+	if err != nil {
+		t.Fatalf("fingerprint: parse html: %v", err)
+	}
+	return NewFingerprint(node)
+}
+
+func TestFingerprint(t *testing.T) {
+	page := "<html><body><p>" + article + "</p></body></html>"
+	f := mustFingerprint(t, page)
+	if f == (Fingerprint{}) {
+		t.Fatalf("fingerprint: expected non-zero fingerprint")
+	}
+
+	// markup, scripts and whitespace do not change the fingerprint
+	g := mustFingerprint(t, "<html><head><script>var x = 1;</script></head>"+
+		"<body><div>"+strings.Replace(article, " ", "\n  ", -1)+"</div></body></html>")
+	if f != g {
+		t.Fatalf("fingerprint: expected %+v, got %+v", f, g)
+	}
+
+	// near-duplicate text
+	g = mustFingerprint(t, "<html><body><p>"+article+"</p><p>Print</p></body></html>")
+	if f.Hash == g.Hash {
+		t.Fatalf("fingerprint: expected different exact hashes")
+	}
+	if d := f.Distance(g); d > DefaultDistance {
+		t.Fatalf("fingerprint: expected near-duplicate distance, got %d", d)
+	}
+
+	// different text
+	g = mustFingerprint(t, "<html><body><p>The quick brown fox jumps over the lazy dog "+
+		"while the five boxing wizards jump quickly.</p></body></html>")
+	if d := f.Distance(g); d <= DefaultDistance {
+		t.Fatalf("fingerprint: expected distinct distance, got %d", d)
+	}
+
+	if f := mustFingerprint(t, "<html><body></body></html>"); f != (Fingerprint{}) {
+		t.Fatalf("fingerprint: expected zero fingerprint, got %+v", f)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	d := NewDuplicates()
+	u1, _ := url.Parse("http://example.com/article")
+	u2, _ := url.Parse("http://example.com/article?print=1")
+	u3, _ := url.Parse("http://example.com/other")
+
+	f := Fingerprint{Hash: 1, SimHash: 0xFFFF0000FFFF0000}
+	if dup := d.Add(u1, f); dup != nil {
+		t.Fatalf("duplicates: expected <nil>, got %q", dup)
+	}
+	if dup := d.Add(u1, f); dup != nil {
+		t.Fatalf("duplicates: expected <nil> for same url, got %q", dup)
+	}
+	if dup := d.Add(u2, f); dup != u1 {
+		t.Fatalf("duplicates: expected exact duplicate %q, got %q", u1, dup)
+	}
+	if dup := d.Add(u2, Fingerprint{Hash: 2, SimHash: f.SimHash ^ 0x0001000100010000}); dup != u1 {
+		t.Fatalf("duplicates: expected near-duplicate %q, got %q", u1, dup)
+	}
+	if dup := d.Add(u3, Fingerprint{Hash: 3, SimHash: ^f.SimHash}); dup != nil {
+		t.Fatalf("duplicates: expected <nil>, got %q", dup)
+	}
+	if dup := d.Add(u3, Fingerprint{}); dup != nil {
+		t.Fatalf("duplicates: expected <nil> for empty page, got %q", dup)
+	}
+}
+
+func TestCrawlerSkipDuplicates(t *testing.T) {
+	t.Parallel()
+
+	mu := &sync.Mutex{}
+	var pages []*Page
+	w := newTestWorker()
+	w.GetFunc = func(u *url.URL) (io.ReadCloser, error) {
+		data := "<html><body><p>" + article + "</p>"
+		if u.Path == "/" {
+			for i := 0; i < 5; i++ {
+				data += fmt.Sprintf(`<a href="/article?session=%d">article</a>`, i)
+			}
+		} else {
+			data += fmt.Sprintf(`<a href="/more?%s">more</a>`, u.RawQuery)
+		}
+		data += "</body></html>"
+		return ioutil.NopCloser(strings.NewReader(data)), nil
+	}
+	w.PageFunc = func(p *Page) {
+		mu.Lock()
+		pages = append(pages, p)
+		mu.Unlock()
+	}
+	w.Duplicates = NewDuplicates()
+	w.SkipDuplicates = true
+
+	c := New(w, time.Millisecond*20, nil)
+	c.Start(nil, w.Host)
+	<-c.Done()
+
+	if len(pages) != 1 {
+		t.Fatalf("duplicates: expected 1 processed page, got %d", len(pages))
+	}
+	if pages[0].Duplicate != nil {
+		t.Fatalf("duplicates: expected no duplicate, got %q", pages[0].Duplicate)
+	}
+}
@@ -0,0 +1,148 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// urlEdges returns the edges of g with the URLs of their nodes as source
+// and target.
+func (g *Graph) urlEdges() []Edge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	edges := make([]Edge, len(g.edges))
+	for i, e := range g.edges {
+		e.Source, e.Target, e.URL = g.url(e.Source), g.url(e.Target), ""
+		edges[i] = e
+	}
+	return edges
+}
+
+// WriteCSV writes the edges of g as CSV with a header line.
+func (g *Graph) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source", "target", "text", "rel", "kind"}); err != nil {
+		return err
+	}
+	for _, e := range g.urlEdges() {
+		if err := cw.Write([]string{e.Source, e.Target, e.Text, e.Rel, e.Kind}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONL writes the edges of g as one JSON object per line.
+func (g *Graph) WriteJSONL(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, e := range g.urlEdges() {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteGraphML writes g in the GraphML format. Nodes carry their URL
+// and inlink count, edges their text, rel and kind.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+		Data   []data `xml:"data"`
+	}
+	type key struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+	type graphml struct {
+		XMLName xml.Name `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+		Keys    []key    `xml:"key"`
+		Graph   struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}
+
+	doc := graphml{Keys: []key{
+		{"url", "node", "url", "string"},
+		{"inlinks", "node", "inlinks", "int"},
+		{"text", "edge", "text", "string"},
+		{"rel", "edge", "rel", "string"},
+		{"kind", "edge", "kind", "string"},
+	}}
+	doc.Graph.EdgeDefault = "directed"
+
+	ids := make(map[string]string)
+	for i, url := range g.Nodes() {
+		id := "n" + strconv.Itoa(i)
+		ids[url] = id
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node{id, []data{
+			{"url", g.URL(url)},
+			{"inlinks", strconv.Itoa(g.Inlinks(url))},
+		}})
+	}
+	for _, e := range g.Edges() {
+		doc.Graph.Edges = append(doc.Graph.Edges, edge{ids[e.Source], ids[e.Target], []data{
+			{"text", e.Text},
+			{"rel", e.Rel},
+			{"kind", e.Kind},
+		}})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteDOT writes g in the Graphviz DOT language.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph links {")
+	for _, url := range g.Nodes() {
+		fmt.Fprintf(bw, "\t%s [inlinks=%d];\n", quote(g.URL(url)), g.Inlinks(url))
+	}
+	for _, e := range g.urlEdges() {
+		fmt.Fprintf(bw, "\t%s -> %s [kind=%s", quote(e.Source), quote(e.Target), quote(e.Kind))
+		if len(e.Text) > 0 {
+			fmt.Fprintf(bw, ", label=%s", quote(e.Text))
+		}
+		if len(e.Rel) > 0 {
+			fmt.Fprintf(bw, ", rel=%s", quote(e.Rel))
+		}
+		fmt.Fprintln(bw, "];")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
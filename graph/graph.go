@@ -0,0 +1,158 @@
+// Package graph records the link graph of a crawl and exports it to
+// CSV, JSONL, GraphML and DOT. The exports name nodes by their URL, see
+// Graph.URL.
+package graph
+
+import (
+	"sort"
+	"sync"
+)
+
+// Edge is a link from a source page to a target URL. Source and Target
+// identify the nodes of the link, URL is the link target as written on
+// the source page, resolved and without fragment.
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	URL    string `json:"url,omitempty"`
+	Text   string `json:"text,omitempty"` // anchor text or image alt text
+	Rel    string `json:"rel,omitempty"`  // rel attribute
+	Kind   string `json:"kind"`           // linking element, such as a, img or link
+}
+
+// Graph is a directed link graph. It is safe for concurrent use.
+type Graph struct {
+	// Key returns the node of a URL given to Depths or Orphans. If nil,
+	// URLs are taken as nodes. A Crawler recording the graph sets Key to
+	// the function naming its nodes if nil.
+	Key func(url string) string
+
+	mu      sync.Mutex
+	edges   []Edge
+	nodes   map[string]int             // node to index
+	urls    map[string]string          // node to URL
+	pages   map[string]bool            // crawled nodes
+	sources map[string]map[string]bool // target to linking sources
+}
+
+// New returns an empty graph.
+func New() *Graph {
+	return &Graph{
+		nodes:   make(map[string]int),
+		urls:    make(map[string]string),
+		pages:   make(map[string]bool),
+		sources: make(map[string]map[string]bool),
+	}
+}
+
+// Add adds the edge e and its source and target nodes to g.
+func (g *Graph) Add(e Edge) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.node(e.Source, "")
+	g.node(e.Target, e.URL)
+	g.pages[e.Source] = true
+	g.edges = append(g.edges, e)
+	set, found := g.sources[e.Target]
+	if !found {
+		set = make(map[string]bool)
+		g.sources[e.Target] = set
+	}
+	set[e.Source] = true
+}
+
+// AddNode adds the crawled page node with the given URL to g, for
+// example a page without links. The URL may be empty. Sources of edges
+// are crawled pages as well.
+func (g *Graph) AddNode(node, url string) {
+	g.mu.Lock()
+	g.node(node, url)
+	g.pages[node] = true
+	g.mu.Unlock()
+}
+
+func (g *Graph) node(node, url string) {
+	if _, found := g.nodes[node]; !found {
+		g.nodes[node] = len(g.nodes)
+	}
+	if _, found := g.urls[node]; !found && len(url) > 0 {
+		g.urls[node] = url
+	}
+}
+
+// URL returns the URL node was first added with, or node itself if it
+// was added without URL.
+func (g *Graph) URL(node string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.url(node)
+}
+
+func (g *Graph) key(url string) string {
+	if g.Key == nil {
+		return url
+	}
+	return g.Key(url)
+}
+
+func (g *Graph) url(node string) string {
+	if url, found := g.urls[node]; found {
+		return url
+	}
+	return node
+}
+
+// Edges returns all edges in the order they were added.
+func (g *Graph) Edges() []Edge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Edge(nil), g.edges...)
+}
+
+// Nodes returns all nodes in the order they were added.
+func (g *Graph) Nodes() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.nodeList()
+}
+
+func (g *Graph) nodeList() []string {
+	nodes := make([]string, len(g.nodes))
+	for node, i := range g.nodes {
+		nodes[i] = node
+	}
+	return nodes
+}
+
+// Inlinks returns the number of distinct pages linking to url.
+func (g *Graph) Inlinks(url string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.sources[url])
+}
+
+// Count is the inlink count of a URL.
+type Count struct {
+	URL     string
+	Inlinks int
+}
+
+// InlinkCounts returns the inlink counts of all nodes ordered by
+// decreasing count.
+func (g *Graph) InlinkCounts() []Count {
+	g.mu.Lock()
+	counts := make([]Count, 0, len(g.nodes))
+	for node := range g.nodes {
+		counts = append(counts, Count{node, len(g.sources[node])})
+	}
+	g.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Inlinks != counts[j].Inlinks {
+			return counts[i].Inlinks > counts[j].Inlinks
+		}
+		return counts[i].URL < counts[j].URL
+	})
+	return counts
+}
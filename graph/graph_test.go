@@ -0,0 +1,130 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func newTestGraph() *Graph {
+	g := New()
+	g.Add(Edge{Source: "http://example.com/", Target: "http://example.com/a", URL: "http://example.com/a/", Text: "A", Kind: "a"})
+	g.Add(Edge{Source: "http://example.com/", Target: "http://example.com/b", Text: `say "b"`, Rel: "nofollow", Kind: "a"})
+	g.Add(Edge{Source: "http://example.com/a", Target: "http://example.com/b", Text: "b, again", Kind: "a"})
+	g.Add(Edge{Source: "http://example.com/a", Target: "http://example.com/b", Kind: "img"})
+	g.AddNode("http://example.com/c", "")
+	return g
+}
+
+func TestInlinks(t *testing.T) {
+	g := newTestGraph()
+
+	want := []Count{
+		{"http://example.com/b", 2},
+		{"http://example.com/a", 1},
+		{"http://example.com/", 0},
+		{"http://example.com/c", 0},
+	}
+	got := g.InlinkCounts()
+	if len(got) != len(want) {
+		t.Fatalf("inlinks: expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("inlinks: expected %v, got %v", want, got)
+		}
+	}
+	if n := g.Inlinks("http://example.com/b"); n != 2 {
+		t.Fatalf("inlinks: expected 2, got %d", n)
+	}
+	if n := len(g.Nodes()); n != 4 {
+		t.Fatalf("nodes: expected 4, got %d", n)
+	}
+	if url := g.URL("http://example.com/a"); url != "http://example.com/a/" {
+		t.Fatalf("url: expected http://example.com/a/, got %s", url)
+	}
+	if url := g.URL("http://example.com/c"); url != "http://example.com/c" {
+		t.Fatalf("url: expected http://example.com/c, got %s", url)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := newTestGraph().WriteCSV(buf); err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	want := `source,target,text,rel,kind
+http://example.com/,http://example.com/a/,A,,a
+http://example.com/,http://example.com/b,"say ""b""",nofollow,a
+http://example.com/a/,http://example.com/b,"b, again",,a
+http://example.com/a/,http://example.com/b,,,img
+`
+	if buf.String() != want {
+		t.Fatalf("csv: expected\n%s\ngot\n%s", want, buf)
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := newTestGraph().WriteJSONL(buf); err != nil {
+		t.Fatalf("jsonl: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("jsonl: expected 4 lines, got %d", len(lines))
+	}
+	var e Edge
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatalf("jsonl: %v", err)
+	}
+	if e.Rel != "nofollow" || e.Text != `say "b"` {
+		t.Fatalf("jsonl: unexpected edge %+v", e)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := newTestGraph().WriteGraphML(buf); err != nil {
+		t.Fatalf("graphml: %v", err)
+	}
+
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				ID string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("graphml: invalid xml: %v", err)
+	}
+	if len(doc.Graph.Nodes) != 4 || len(doc.Graph.Edges) != 4 {
+		t.Fatalf("graphml: expected 4 nodes and 4 edges, got %d and %d",
+			len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+	if e := doc.Graph.Edges[2]; e.Source != "n1" || e.Target != "n2" {
+		t.Fatalf("graphml: expected edge n1 -> n2, got %s -> %s", e.Source, e.Target)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := newTestGraph().WriteDOT(buf); err != nil {
+		t.Fatalf("dot: %v", err)
+	}
+	for _, want := range []string{
+		"digraph links {\n",
+		"\t\"http://example.com/b\" [inlinks=2];\n",
+		"\t\"http://example.com/\" -> \"http://example.com/b\" [kind=\"a\", label=\"say \\\"b\\\"\", rel=\"nofollow\"];\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("dot: expected %q in\n%s", want, buf)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	DefaultDamping    = 0.85 // PageRank damping factor
+	DefaultIterations = 100  // maximum number of iterations
+	epsilon           = 1e-9 // convergence threshold
+)
+
+// adjacency returns the nodes of g and the distinct outlinks of every
+// node by node index. Only anchor (a) links are followed, image, script
+// and other resource links are not navigation. If pages is set, the
+// nodes are restricted to crawled pages, so links to assets, other hosts
+// or pages not crawled do not take part. Self-links are ignored.
+func (g *Graph) adjacency(pages bool) ([]string, [][]int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var nodes []string
+	index := make(map[string]int, len(g.nodes))
+	for _, node := range g.nodeList() {
+		if !pages || g.pages[node] {
+			index[node] = len(nodes)
+			nodes = append(nodes, node)
+		}
+	}
+	out := make([][]int, len(nodes))
+	seen := make(map[[2]int]bool)
+	for _, e := range g.edges {
+		if e.Kind != "a" {
+			continue
+		}
+		from, ok := index[e.Source]
+		to, found := index[e.Target]
+		if !ok || !found || from == to || seen[[2]int{from, to}] {
+			continue
+		}
+		seen[[2]int{from, to}] = true
+		out[from] = append(out[from], to)
+	}
+	return nodes, out
+}
+
+// PageRank returns the PageRank of every crawled page of g using the
+// damping factor damping, following anchor links between crawled pages
+// only. The computation stops after iterations or as soon as the ranks
+// converge. The ranks sum up to 1.
+func (g *Graph) PageRank(damping float64, iterations int) map[string]float64 {
+	nodes, out := g.adjacency(true)
+	n := len(nodes)
+	if n == 0 {
+		return map[string]float64{}
+	}
+
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	for iter := 0; iter < iterations; iter++ {
+		var dangling float64 // rank of nodes without outlinks
+		for i := range rank {
+			if len(out[i]) == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, links := range out {
+			share := damping * rank[i] / float64(len(links))
+			for _, j := range links {
+				next[j] += share
+			}
+		}
+
+		var delta float64
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < epsilon {
+			break
+		}
+	}
+
+	ranks := make(map[string]float64, n)
+	for i, node := range nodes {
+		ranks[node] = rank[i]
+	}
+	return ranks
+}
+
+// HITS returns the hub and authority scores of every crawled page of g
+// computed with the HITS algorithm, following anchor links between
+// crawled pages only. The computation stops after iterations or as soon
+// as the scores converge. Both score vectors have unit length.
+func (g *Graph) HITS(iterations int) (hubs, authorities map[string]float64) {
+	nodes, out := g.adjacency(true)
+	n := len(nodes)
+
+	hub := make([]float64, n)
+	auth := make([]float64, n)
+	for i := range hub {
+		hub[i] = 1
+	}
+	for iter := 0; iter < iterations; iter++ {
+		next := make([]float64, n)
+		for i, links := range out {
+			for _, j := range links {
+				next[j] += hub[i]
+			}
+		}
+		normalize(next)
+		auth = next
+
+		next = make([]float64, n)
+		for i, links := range out {
+			for _, j := range links {
+				next[i] += auth[j]
+			}
+		}
+		normalize(next)
+
+		var delta float64
+		for i := range hub {
+			delta += math.Abs(next[i] - hub[i])
+		}
+		hub = next
+		if delta < epsilon {
+			break
+		}
+	}
+
+	hubs = make(map[string]float64, n)
+	authorities = make(map[string]float64, n)
+	for i, node := range nodes {
+		hubs[node] = hub[i]
+		authorities[node] = auth[i]
+	}
+	return hubs, authorities
+}
+
+func normalize(v []float64) {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// Depths returns the click depth of every node reachable from the seed
+// URLs, that is the minimum number of anchor links to follow from any
+// seed. Seeds have depth zero.
+func (g *Graph) Depths(seeds ...string) map[string]int {
+	nodes, out := g.adjacency(false)
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node] = i
+	}
+
+	depths := make(map[string]int)
+	var queue []int
+	for _, seed := range seeds {
+		if i, found := index[g.key(seed)]; found {
+			if _, seen := depths[nodes[i]]; !seen {
+				depths[nodes[i]] = 0
+				queue = append(queue, i)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, j := range out[i] {
+			if _, seen := depths[nodes[j]]; !seen {
+				depths[nodes[j]] = depths[nodes[i]] + 1
+				queue = append(queue, j)
+			}
+		}
+	}
+	return depths
+}
+
+// Orphans returns the sorted URLs of urls, for example the URLs of a
+// sitemap, which no other page of g links to.
+func (g *Graph) Orphans(urls []string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var orphans []string
+	for _, url := range urls {
+		node := g.key(url)
+		linked := false
+		for source := range g.sources[node] {
+			if source != node {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			orphans = append(orphans, url)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
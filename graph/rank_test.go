@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// newRankGraph returns the graph
+//
+//	a -> b, a -> c, b -> c, c -> a, d -> c
+func newRankGraph() *Graph {
+	g := New()
+	for _, e := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "c"}, {"c", "a"}, {"d", "c"}, {"a", "b"}} {
+		g.Add(Edge{Source: e[0], Target: e[1], Kind: "a"})
+	}
+	return g
+}
+
+func TestPageRank(t *testing.T) {
+	g := newRankGraph()
+	// neither resource links nor links to pages not crawled are ranked
+	g.Add(Edge{Source: "d", Target: "b", Kind: "img"})
+	g.Add(Edge{Source: "b", Target: "x", Kind: "a"})
+	ranks := g.PageRank(DefaultDamping, DefaultIterations)
+	if _, found := ranks["x"]; found || len(ranks) != 4 {
+		t.Fatalf("pagerank: expected ranks of 4 pages, got %v", ranks)
+	}
+
+	var sum float64
+	for _, r := range ranks {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Fatalf("pagerank: expected ranks summing up to 1, got %v", sum)
+	}
+
+	// reference values computed by plain power iteration
+	for node, want := range map[string]float64{
+		"a": 0.372526, "b": 0.195824, "c": 0.394150, "d": 0.0375,
+	} {
+		if math.Abs(ranks[node]-want) > 1e-4 {
+			t.Errorf("pagerank %s: expected %.6f, got %.6f", node, want, ranks[node])
+		}
+	}
+
+	if ranks := New().PageRank(DefaultDamping, DefaultIterations); len(ranks) != 0 {
+		t.Fatalf("pagerank: expected no ranks, got %v", ranks)
+	}
+}
+
+func TestHITS(t *testing.T) {
+	hubs, auths := newRankGraph().HITS(DefaultIterations)
+
+	if !(auths["c"] > auths["b"] && auths["b"] > auths["d"]) || auths["d"] != 0 {
+		t.Fatalf("hits: unexpected authorities %v", auths)
+	}
+	if !(hubs["a"] > hubs["b"] && hubs["b"] > hubs["c"]) {
+		t.Fatalf("hits: unexpected hubs %v", hubs)
+	}
+}
+
+func TestDepths(t *testing.T) {
+	g := newRankGraph()
+	g.Add(Edge{Source: "c", Target: "e", Kind: "a"})
+	g.Add(Edge{Source: "d", Target: "f", Kind: "img"})
+	g.Key = func(url string) string { return strings.TrimSuffix(url, "/") }
+
+	depths := g.Depths("a/", "x")
+	want := map[string]int{"a": 0, "b": 1, "c": 1, "e": 2}
+	if len(depths) != len(want) {
+		t.Fatalf("depths: expected %v, got %v", want, depths)
+	}
+	for node, d := range want {
+		if depths[node] != d {
+			t.Fatalf("depths: expected %v, got %v", want, depths)
+		}
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	g := newRankGraph()
+	g.Add(Edge{Source: "e", Target: "e", Kind: "a"})
+	g.Key = func(url string) string { return strings.TrimSuffix(url, "/") }
+
+	got := g.Orphans([]string{"a/", "d", "e", "f"})
+	want := []string{"d", "e", "f"}
+	if len(got) != len(want) {
+		t.Fatalf("orphans: expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("orphans: expected %v, got %v", want, got)
+		}
+	}
+}
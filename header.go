@@ -0,0 +1,53 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+// header returns the headers of a request of a URL discovered on the page
+// referrer, which may be empty. Host headers are applied by the client.
+func (w *Worker) header(referrer string) http.Header {
+	h := http.Header{}
+	if agent := w.userAgent(); len(agent) > 0 {
+		h.Set("User-Agent", agent)
+	}
+	if w.Referer && len(referrer) > 0 {
+		h.Set("Referer", referrer)
+	}
+	for key, values := range w.Header {
+		h[http.CanonicalHeaderKey(key)] = values
+	}
+	return h
+}
+
+// userAgent returns the next user agent of UserAgents, or UserAgent.
+func (w *Worker) userAgent() string {
+	if len(w.UserAgents) == 0 {
+		return w.UserAgent
+	}
+	i := atomic.AddUint32(&w.agent, 1) - 1
+	return w.UserAgents[int(i%uint32(len(w.UserAgents)))]
+}
+
+// robots returns the robots.txt rules of the worker. Rules implementing
+// AgentRobots are tested with RobotsAgent.
+func (w *Worker) robots() Robots {
+	r, ok := w.Robots.(AgentRobots)
+	if !ok {
+		return w.Robots
+	}
+	agent := w.RobotsAgent
+	if len(agent) == 0 {
+		agent = DefaultRobotsAgent
+	}
+	return agentRobots{r, agent}
+}
+
+type agentRobots struct {
+	AgentRobots
+	agent string
+}
+
+func (r agentRobots) Test(url *url.URL) bool { return r.TestAgent(url, r.agent) }
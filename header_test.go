@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+type agentTestRobots struct {
+	mu     sync.Mutex
+	agents map[string]bool
+}
+
+func (r *agentTestRobots) Test(url *url.URL) bool { return r.TestAgent(url, "") }
+
+func (r *agentTestRobots) TestAgent(url *url.URL, agent string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.agents[agent] = true
+	return url.Path != "/private"
+}
+
+func TestWorkerHeader(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	requests := make(map[string]http.Header)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests[req.URL.Path] = req.Header
+		mu.Unlock()
+		switch req.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="/a#top">a</a><a href="/private">p</a></body></html>`))
+		case "/a":
+			w.Write([]byte(`<html><body><a href="/b">b</a></body></html>`))
+		default:
+			w.Write([]byte(`<html><body></body></html>`))
+		}
+	}))
+	defer s.Close()
+
+	robots := &agentTestRobots{agents: make(map[string]bool)}
+	w := newTestWorker()
+	w.GetFunc = nil
+	w.Concurrent = 1
+	w.Host = mustParseURL(s.URL)
+	w.UserAgents = []string{"agent/1", "agent/2"}
+	w.Header = http.Header{"accept": {"text/html"}, "Accept-Language": {"en"}, "X-Crawl": {"test"}}
+	w.HostHeader = map[string]http.Header{w.Host.Host: {"Accept-Language": {"de"}}}
+	w.Referer = true
+	w.RobotsAgent = "examplebot"
+	w.Robots = robots
+
+	c := New(w, time.Millisecond*50, nil)
+	c.Start(nil, mustParseURL(s.URL+"/"))
+	<-c.Done()
+
+	if len(requests) != 3 {
+		t.Fatalf("header: expected 3 requests, got %v", requests)
+	}
+	agents := make(map[string]bool)
+	for path, h := range requests {
+		agents[h.Get("User-Agent")] = true
+		if h.Get("Accept") != "text/html" || h.Get("Accept-Language") != "de" || h.Get("X-Crawl") != "test" {
+			t.Fatalf("header: unexpected headers of %s: %v", path, h)
+		}
+	}
+	if len(agents) != 2 || !agents["agent/1"] || !agents["agent/2"] {
+		t.Fatalf("header: expected rotated user agents, got %v", agents)
+	}
+	if ref := requests["/"].Get("Referer"); ref != "" {
+		t.Fatalf("header: expected no referer of seed, got %q", ref)
+	}
+	if ref := requests["/a"].Get("Referer"); ref != s.URL+"/" {
+		t.Fatalf("header: expected referer %q, got %q", s.URL+"/", ref)
+	}
+	if ref := requests["/b"].Get("Referer"); ref != s.URL+"/a" {
+		t.Fatalf("header: expected referer %q, got %q", s.URL+"/a", ref)
+	}
+	if len(robots.agents) != 1 || !robots.agents["examplebot"] {
+		t.Fatalf("header: expected robots agent examplebot, got %v", robots.agents)
+	}
+}
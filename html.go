@@ -2,7 +2,10 @@ package crawler
 
 import (
 	"bytes"
+	"net/url"
+	"strings"
 
+	"github.com/mars9/crawler/graph"
 	"golang.org/x/net/html"
 )
 
@@ -13,3 +16,179 @@ func parseHTML(data []byte) (*html.Node, error) {
 	}
 	return node, nil
 }
+
+// linkAttrs maps elements to the attribute holding their link reference.
+var linkAttrs = map[string]string{
+	"a":      "href",
+	"area":   "href",
+	"link":   "href",
+	"img":    "src",
+	"script": "src",
+	"iframe": "src",
+	"frame":  "src",
+	"embed":  "src",
+	"source": "src",
+	"audio":  "src",
+	"video":  "src",
+}
+
+// linkAttr returns the non-empty attribute of node holding a link
+// reference, or nil if node does not link to another resource.
+func linkAttr(node *html.Node) *html.Attribute {
+	if node.Type != html.ElementNode {
+		return nil
+	}
+	key, found := linkAttrs[node.Data]
+	if !found {
+		return nil
+	}
+	for i := range node.Attr {
+		if node.Attr[i].Key == key && node.Attr[i].Val != "" {
+			return &node.Attr[i]
+		}
+	}
+	return nil
+}
+
+// text returns the text content of node, skipping scripts and styles.
+// Text of different nodes is separated by a space.
+func text(node *html.Node) string {
+	buf := &bytes.Buffer{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript", "template":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return buf.String()
+}
+
+// newEdge returns the link graph edge of the link element node on the
+// page parent pointing to target.
+func newEdge(parent, target *url.URL, node *html.Node) graph.Edge {
+	link := *target
+	link.Fragment, link.RawFragment = "", ""
+	e := graph.Edge{
+		Source: urlKey(parent),
+		Target: urlKey(target),
+		URL:    link.String(),
+		Text:   strings.Join(strings.Fields(text(node)), " "),
+		Kind:   node.Data,
+	}
+	for _, attr := range node.Attr {
+		switch attr.Key {
+		case "rel":
+			e.Rel = attr.Val
+		case "alt":
+			if len(e.Text) == 0 {
+				e.Text = attr.Val
+			}
+		}
+	}
+	return e
+}
+
+// find returns the first element of node and its descendants for which
+// match returns true, or nil.
+func find(node *html.Node, match func(*html.Node) bool) *html.Node {
+	if node.Type == html.ElementNode && match(node) {
+		return node
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if n := find(c, match); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// title returns the normalized text of the title element of node.
+func title(node *html.Node) string {
+	n := find(node, func(n *html.Node) bool { return n.Data == "title" })
+	if n == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(text(n)), " ")
+}
+
+// metaContent returns the content of the meta element of node with the
+// given name, compared case-insensitively.
+func metaContent(node *html.Node, name string) string {
+	n := find(node, func(n *html.Node) bool {
+		return n.Data == "meta" && strings.EqualFold(attr(n, "name"), name)
+	})
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(attr(n, "content"))
+}
+
+// linkRel returns the href of the first link element of node with the
+// relation rel, resolved against parent.
+func linkRel(parent *url.URL, node *html.Node, rel string) string {
+	n := find(node, func(n *html.Node) bool {
+		if n.Data != "link" {
+			return false
+		}
+		for _, r := range strings.Fields(attr(n, "rel")) {
+			if strings.EqualFold(r, rel) {
+				return true
+			}
+		}
+		return false
+	})
+	if n == nil || len(attr(n, "href")) == 0 {
+		return ""
+	}
+	u, err := normalize(parent, attr(n, "href"))
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// outlinks returns the distinct targets of the anchors of node resolved
+// against parent, in document order.
+func outlinks(parent *url.URL, node *html.Node) []string {
+	var links []string
+	seen := make(map[string]bool)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			if a := linkAttr(n); a != nil {
+				if u, err := normalize(parent, a.Val); err == nil && !seen[u.String()] {
+					seen[u.String()] = true
+					links = append(links, u.String())
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return links
+}
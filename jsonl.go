@@ -0,0 +1,218 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// PageRecord is the JSON representation of a fetched page written by
+// JSONLWriter.
+type PageRecord struct {
+	URL         string      `json:"url"`
+	FinalURL    string      `json:"final_url"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"headers"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Canonical   string      `json:"canonical"`
+	Outlinks    []string    `json:"outlinks"`
+	Text        string      `json:"text"`
+	Fetched     time.Time   `json:"fetched"`
+	Depth       int         `json:"depth"`
+	Language    string      `json:"language"`
+
+	Record map[string]interface{} `json:"record,omitempty"`
+}
+
+// NewPageRecord returns the record of page.
+func NewPageRecord(page *Page) *PageRecord {
+	final := page.FinalURL
+	if final == nil {
+		final = page.URL
+	}
+	r := &PageRecord{
+		URL:      page.URL.String(),
+		FinalURL: final.String(),
+		Status:   page.Status,
+		Header:   page.Header,
+		Fetched:  page.Fetched,
+		Depth:    page.Depth,
+		Language: page.Language,
+		Record:   page.Record,
+		Outlinks: []string{},
+	}
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	if page.Node != nil {
+		r.Title = title(page.Node)
+		r.Description = metaContent(page.Node, "description")
+		r.Canonical = linkRel(final, page.Node, "canonical")
+		if links := outlinks(final, page.Node); links != nil {
+			r.Outlinks = links
+		}
+		body := find(page.Node, func(n *html.Node) bool { return n.Data == "body" })
+		if body == nil {
+			body = page.Node
+		}
+		r.Text = strings.Join(strings.Fields(text(body)), " ")
+	}
+	return r
+}
+
+// JSONLWriter writes fetched pages as JSON lines, one PageRecord per
+// line. It is safe for concurrent use by the workers of a crawl.
+type JSONLWriter struct {
+	mu   sync.Mutex
+	file io.WriteCloser // current output file, nil if not owned
+	zw   *gzip.Writer
+	bw   *bufio.Writer
+
+	compress bool
+	name     string // file name of rotated files
+	maxSize  int64  // uncompressed size limit of rotated files
+	size     int64  // uncompressed size of the current output
+	seq      int    // sequence number of the current file
+}
+
+// NewJSONLWriter returns a writer writing records to w. If compress is
+// true the output is gzip compressed.
+func NewJSONLWriter(w io.Writer, compress bool) *JSONLWriter {
+	jw := &JSONLWriter{compress: compress}
+	jw.reset(w)
+	return jw
+}
+
+// NewJSONLFile returns a writer writing records to files named after
+// name. If maxSize is positive a new file is started as soon as the
+// current one holds maxSize uncompressed bytes, and the sequence number
+// of the file, starting at zero, is inserted before the .jsonl,
+// .jsonl.gz, .json or .json.gz extension of name, or else before its
+// last extension, as in pages-00000.jsonl.gz. Files are gzip compressed
+// if name ends in .gz.
+func NewJSONLFile(name string, maxSize int64) (*JSONLWriter, error) {
+	jw := &JSONLWriter{
+		compress: strings.HasSuffix(name, ".gz"),
+		name:     name,
+		maxSize:  maxSize,
+	}
+	if err := jw.open(); err != nil {
+		return nil, err
+	}
+	return jw, nil
+}
+
+// Name returns the name of the current output file, or an empty string
+// if jw does not write to a file.
+func (jw *JSONLWriter) Name() string {
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	return jw.fileName()
+}
+
+func (jw *JSONLWriter) fileName() string {
+	if len(jw.name) == 0 || jw.maxSize <= 0 {
+		return jw.name
+	}
+	dir, base := filepath.Split(jw.name)
+	ext := filepath.Ext(base)
+	for _, e := range jsonlExts {
+		if strings.HasSuffix(base, e) {
+			ext = e
+			break
+		}
+	}
+	if ext == base { // hidden file without extension
+		ext = ""
+	}
+	base = base[:len(base)-len(ext)]
+	return filepath.Join(dir, fmt.Sprintf("%s-%05d%s", base, jw.seq, ext))
+}
+
+// jsonlExts are the extensions of rotated files kept as a whole.
+var jsonlExts = []string{".jsonl.gz", ".json.gz", ".jsonl", ".json"}
+
+func (jw *JSONLWriter) reset(w io.Writer) {
+	jw.zw, jw.size = nil, 0
+	if jw.compress {
+		jw.zw = gzip.NewWriter(w)
+		w = jw.zw
+	}
+	jw.bw = bufio.NewWriter(w)
+}
+
+func (jw *JSONLWriter) open() error {
+	f, err := os.Create(jw.fileName())
+	if err != nil {
+		return err
+	}
+	jw.file = f
+	jw.reset(f)
+	return nil
+}
+
+// flush flushes and closes the current output.
+func (jw *JSONLWriter) flush() error {
+	if jw.bw == nil {
+		return nil
+	}
+	err := jw.bw.Flush()
+	if jw.zw != nil {
+		if zerr := jw.zw.Close(); err == nil {
+			err = zerr
+		}
+	}
+	if jw.file != nil {
+		if ferr := jw.file.Close(); err == nil {
+			err = ferr
+		}
+	}
+	jw.bw, jw.zw, jw.file = nil, nil, nil
+	return err
+}
+
+// Write writes the record of page.
+func (jw *JSONLWriter) Write(page *Page) error {
+	data, err := json.Marshal(NewPageRecord(page))
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	if jw.bw == nil {
+		return os.ErrClosed
+	}
+	if jw.maxSize > 0 && jw.size > 0 && jw.size+int64(len(data)) > jw.maxSize {
+		if err = jw.flush(); err != nil {
+			return err
+		}
+		jw.seq++
+		if err = jw.open(); err != nil {
+			return err
+		}
+	}
+	n, err := jw.bw.Write(data)
+	jw.size += int64(n)
+	return err
+}
+
+// Close flushes buffered records and closes the current output file. It
+// does not close the io.Writer passed to NewJSONLWriter.
+func (jw *JSONLWriter) Close() error {
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	return jw.flush()
+}
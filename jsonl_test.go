@@ -0,0 +1,205 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const recordPage = `<html>
+<head>
+	<title>  Example
+	page </title>
+	<meta name="Description" content=" An example. ">
+	<link rel="stylesheet" href="/style.css">
+	<link rel="alternate canonical" href="/page">
+</head>
+<body>
+	<h1>Hello</h1>
+	<script>var x = 1;</script>
+	<p>world <a href="/a">a</a> <a href="b#top">b</a> <a href="/a">again</a></p>
+</body>
+</html>`
+
+func TestPageRecord(t *testing.T) {
+	node, err := parseHTML([]byte(recordPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &Page{
+		URL:      mustParseURL("http://example.com/old"),
+		FinalURL: mustParseURL("http://example.com/dir/page?x=1"),
+		Node:     node,
+		Status:   200,
+		Depth:    2,
+		Language: "en",
+	}
+
+	r := NewPageRecord(page)
+	if r.URL != "http://example.com/old" || r.FinalURL != "http://example.com/dir/page?x=1" {
+		t.Fatalf("record: unexpected urls %q, %q", r.URL, r.FinalURL)
+	}
+	if r.Title != "Example page" || r.Description != "An example." {
+		t.Fatalf("record: unexpected title %q, description %q", r.Title, r.Description)
+	}
+	if r.Canonical != "http://example.com/page" {
+		t.Fatalf("record: unexpected canonical %q", r.Canonical)
+	}
+	want := []string{"http://example.com/a", "http://example.com/dir/page/b#top"}
+	if !reflect.DeepEqual(r.Outlinks, want) {
+		t.Fatalf("record: expected outlinks %v, got %v", want, r.Outlinks)
+	}
+	if r.Text != "Hello world a b again" {
+		t.Fatalf("record: unexpected text %q", r.Text)
+	}
+	if r.Depth != 2 || r.Status != 200 || r.Header == nil || r.Language != "en" {
+		t.Fatalf("record: unexpected record %+v", r)
+	}
+}
+
+func TestJSONLWriterRotate(t *testing.T) {
+	page := &Page{URL: mustParseURL("http://example.com/" + strings.Repeat("x", 200))}
+	data, _ := json.Marshal(NewPageRecord(page))
+
+	// two records per file
+	name := filepath.Join(t.TempDir(), "pages.jsonl.gz")
+	jw, err := NewJSONLFile(name, int64(2*len(data)+2))
+	if err != nil {
+		t.Fatalf("jsonl: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := jw.Write(page); err != nil {
+			t.Fatalf("jsonl: write: %v", err)
+		}
+	}
+	last := jw.Name()
+	if err := jw.Close(); err != nil {
+		t.Fatalf("jsonl: close: %v", err)
+	}
+	if err := jw.Write(&Page{URL: mustParseURL("http://example.com/")}); err != os.ErrClosed {
+		t.Fatalf("jsonl: expected %v, got %v", os.ErrClosed, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(name), "pages-*.jsonl.gz"))
+	if len(files) != 3 || files[2] != last || filepath.Base(files[0]) != "pages-00000.jsonl.gz" {
+		t.Fatalf("jsonl: unexpected files %v", files)
+	}
+	records := 0
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("jsonl: %s: %v", file, err)
+		}
+		scanner := bufio.NewScanner(zr)
+		for scanner.Scan() {
+			var r PageRecord
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				t.Fatalf("jsonl: %s: %v", file, err)
+			}
+			records++
+		}
+	}
+	if records != 5 {
+		t.Fatalf("jsonl: expected 5 records, got %d", records)
+	}
+}
+
+func TestJSONLFileName(t *testing.T) {
+	for _, test := range []struct {
+		name, want string
+	}{
+		{"pages.jsonl.gz", "pages-00001.jsonl.gz"},
+		{"out.2024.jsonl.gz", "out.2024-00001.jsonl.gz"},
+		{"crawl.d/out.2024.jsonl", "crawl.d/out.2024-00001.jsonl"},
+		{"crawl.d/out.2024.json.gz", "crawl.d/out.2024-00001.json.gz"},
+		{"crawl.d/pages.txt", "crawl.d/pages-00001.txt"},
+		{"crawl.d/pages", "crawl.d/pages-00001"},
+		{".jsonl", ".jsonl-00001"},
+	} {
+		jw := &JSONLWriter{name: filepath.FromSlash(test.name), maxSize: 1, seq: 1}
+		if got := jw.fileName(); got != filepath.FromSlash(test.want) {
+			t.Fatalf("jsonl %s: expected %s, got %s", test.name, test.want, got)
+		}
+	}
+}
+
+func TestCrawlerJSONL(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`<html><body><a href="/old">old</a></body></html>`))
+	})
+	mux.Handle("/old", http.RedirectHandler("/new", http.StatusMovedPermanently))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`<html><body><a href="/deep">deep</a></body></html>`))
+	})
+	mux.HandleFunc("/deep", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`<html><body><a href="/">home</a></body></html>`))
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	buf := &bytes.Buffer{}
+	jw := NewJSONLWriter(buf, false)
+	w := &Worker{
+		PageFunc: func(page *Page) {
+			if err := jw.Write(page); err != nil {
+				t.Errorf("jsonl: write: %v", err)
+			}
+		},
+		Concurrent: 2,
+	}
+	w.Host, _ = url.Parse(s.URL)
+	c := New(w, 50*time.Millisecond, nil)
+	c.Start(nil, mustParseURL(s.URL+"/"))
+	<-c.Done()
+	if err := jw.Close(); err != nil {
+		t.Fatalf("jsonl: close: %v", err)
+	}
+
+	records := make(map[string]PageRecord)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r PageRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("jsonl: %v", err)
+		}
+		records[strings.TrimPrefix(r.URL, s.URL)] = r
+	}
+	if len(records) != 3 {
+		t.Fatalf("jsonl: expected 3 records, got %v", records)
+	}
+	for path, depth := range map[string]int{"/": 0, "/old": 1, "/deep": 2} {
+		if records[path].Depth != depth {
+			t.Fatalf("jsonl: expected depth %d of %s, got %d", depth, path, records[path].Depth)
+		}
+	}
+	r := records["/old"]
+	if r.FinalURL != s.URL+"/new" || r.Status != 200 || r.Fetched.IsZero() ||
+		!strings.HasPrefix(http.Header(r.Header).Get("Content-Type"), "text/html") {
+		t.Fatalf("jsonl: unexpected record %+v", r)
+	}
+}
+
+func mustParseURL(rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
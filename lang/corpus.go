@@ -0,0 +1,145 @@
+package lang
+
+// corpus holds the training text of the bundled language profiles.
+var corpus = map[string]string{
+	"en": `All human beings are born free and equal in dignity and rights.
+They are endowed with reason and conscience and should act towards one
+another in a spirit of brotherhood. Everyone is entitled to all the rights
+and freedoms set forth in this declaration, without distinction of any
+kind, such as race, colour, sex, language, religion, political or other
+opinion, national or social origin, property, birth or other status.
+Everyone has the right to life, liberty and security of person. The
+weather was fine this morning, so we walked through the old town and had
+breakfast at a small café near the river. Would you like to read the
+latest news about the city? Our shop offers free delivery on all orders,
+and you can return any item within thirty days. What have they been
+doing there with the children which were waiting for the train? It is
+the most important thing that we can do, because people need help when
+they are in trouble and nobody else will listen to them.`,
+
+	"de": `Alle Menschen sind frei und gleich an Würde und Rechten geboren. Sie
+sind mit Vernunft und Gewissen begabt und sollen einander im Geist der
+Brüderlichkeit begegnen. Jeder hat Anspruch auf die in dieser Erklärung
+verkündeten Rechte und Freiheiten ohne irgendeinen Unterschied, etwa nach
+Rasse, Hautfarbe, Geschlecht, Sprache, Religion, politischer oder
+sonstiger Überzeugung, nationaler oder sozialer Herkunft, Vermögen, Geburt
+oder sonstigem Stand. Jeder hat das Recht auf Leben, Freiheit und
+Sicherheit der Person. Das Wetter war heute Morgen schön, deshalb sind wir
+durch die Altstadt gelaufen und haben in einem kleinen Café am Fluss
+gefrühstückt. Möchten Sie die neuesten Nachrichten aus der Stadt lesen?
+Unser Geschäft bietet kostenlosen Versand für alle Bestellungen, und Sie
+können jeden Artikel innerhalb von dreißig Tagen zurückgeben. Was haben
+sie dort mit den Kindern gemacht, die auf den Zug gewartet haben? Es ist
+das Wichtigste, was wir tun können, weil die Leute Hilfe brauchen, wenn
+sie in Schwierigkeiten sind und niemand sonst ihnen zuhört.`,
+
+	"fr": `Tous les êtres humains naissent libres et égaux en dignité et en
+droits. Ils sont doués de raison et de conscience et doivent agir les uns
+envers les autres dans un esprit de fraternité. Chacun peut se prévaloir
+de tous les droits et de toutes les libertés proclamés dans la présente
+déclaration, sans distinction aucune, notamment de race, de couleur, de
+sexe, de langue, de religion, d'opinion politique ou de toute autre
+opinion, d'origine nationale ou sociale, de fortune, de naissance ou de
+toute autre situation. Tout individu a droit à la vie, à la liberté et à
+la sûreté de sa personne. Il faisait beau ce matin, alors nous avons
+traversé la vieille ville et pris le petit déjeuner dans un petit café
+près de la rivière. Voulez-vous lire les dernières nouvelles de la ville?
+Notre boutique offre la livraison gratuite pour toutes les commandes, et
+vous pouvez retourner chaque article dans un délai de trente jours.
+Qu'est-ce qu'ils ont fait là-bas avec les enfants qui attendaient le
+train? C'est la chose la plus importante que nous puissions faire, parce
+que les gens ont besoin d'aide quand ils sont en difficulté.`,
+
+	"es": `Todos los seres humanos nacen libres e iguales en dignidad y derechos
+y, dotados como están de razón y conciencia, deben comportarse
+fraternalmente los unos con los otros. Toda persona tiene todos los
+derechos y libertades proclamados en esta declaración, sin distinción
+alguna de raza, color, sexo, idioma, religión, opinión política o de
+cualquier otra índole, origen nacional o social, posición económica,
+nacimiento o cualquier otra condición. Todo individuo tiene derecho a la
+vida, a la libertad y a la seguridad de su persona. Hacía buen tiempo esta
+mañana, así que caminamos por el casco antiguo y desayunamos en una
+pequeña cafetería cerca del río. ¿Quiere leer las últimas noticias de la
+ciudad? Nuestra tienda ofrece envío gratuito en todos los pedidos, y
+puede devolver cualquier artículo en un plazo de treinta días. ¿Qué
+hicieron allí con los niños que estaban esperando el tren? Es lo más
+importante que podemos hacer, porque la gente necesita ayuda cuando tiene
+problemas y nadie más los escucha.`,
+
+	"it": `Tutti gli esseri umani nascono liberi ed eguali in dignità e diritti.
+Essi sono dotati di ragione e di coscienza e devono agire gli uni verso
+gli altri in spirito di fratellanza. Ad ogni individuo spettano tutti i
+diritti e tutte le libertà enunciate nella presente dichiarazione, senza
+distinzione alcuna, per ragioni di razza, di colore, di sesso, di lingua,
+di religione, di opinione politica o di altro genere, di origine nazionale
+o sociale, di ricchezza, di nascita o di altra condizione. Ogni individuo
+ha diritto alla vita, alla libertà ed alla sicurezza della propria
+persona. Questa mattina faceva bel tempo, così abbiamo passeggiato per il
+centro storico e fatto colazione in un piccolo bar vicino al fiume. Vuole
+leggere le ultime notizie della città? Il nostro negozio offre la
+spedizione gratuita per tutti gli ordini, e può restituire qualsiasi
+articolo entro trenta giorni. Che cosa hanno fatto là con i bambini che
+aspettavano il treno? È la cosa più importante che possiamo fare, perché
+le persone hanno bisogno di aiuto quando sono in difficoltà.`,
+
+	"nl": `Alle mensen worden vrij en gelijk in waardigheid en rechten geboren.
+Zij zijn begiftigd met verstand en geweten, en behoren zich jegens elkander
+in een geest van broederschap te gedragen. Een ieder heeft aanspraak op
+alle rechten en vrijheden, uiteengezet in deze verklaring, zonder
+onderscheid van welke aard ook, zoals ras, kleur, geslacht, taal,
+godsdienst, politieke of andere overtuiging, nationale of maatschappelijke
+afkomst, eigendom, geboorte of andere status. Een ieder heeft het recht op
+leven, vrijheid en onschendbaarheid van zijn persoon. Het weer was
+vanochtend mooi, dus we liepen door de oude binnenstad en hebben ontbeten
+in een klein café bij de rivier. Wilt u het laatste nieuws uit de stad
+lezen? Onze winkel biedt gratis verzending voor alle bestellingen, en u
+kunt elk artikel binnen dertig dagen terugsturen. Wat hebben ze daar
+gedaan met de kinderen die op de trein stonden te wachten? Het is het
+belangrijkste wat wij kunnen doen, omdat mensen hulp nodig hebben als ze
+in moeilijkheden zijn en niemand anders naar hen luistert.`,
+
+	"pt": `Todos os seres humanos nascem livres e iguais em dignidade e em
+direitos. Dotados de razão e de consciência, devem agir uns para com os
+outros em espírito de fraternidade. Todos os seres humanos podem invocar
+os direitos e as liberdades proclamados na presente declaração, sem
+distinção alguma, nomeadamente de raça, de cor, de sexo, de língua, de
+religião, de opinião política ou outra, de origem nacional ou social, de
+fortuna, de nascimento ou de qualquer outra situação. Todo o indivíduo tem
+direito à vida, à liberdade e à segurança pessoal. O tempo estava bom
+hoje de manhã, então caminhámos pelo centro histórico e tomámos o pequeno
+almoço num pequeno café perto do rio. Quer ler as últimas notícias da
+cidade? A nossa loja oferece envio gratuito em todas as encomendas, e
+pode devolver qualquer artigo no prazo de trinta dias. O que é que eles
+fizeram lá com as crianças que estavam à espera do comboio? É a coisa
+mais importante que podemos fazer, porque as pessoas precisam de ajuda
+quando estão em dificuldades e mais ninguém as ouve.`,
+
+	"pl": `Wszyscy ludzie rodzą się wolni i równi pod względem swej godności i
+swych praw. Są oni obdarzeni rozumem i sumieniem i powinni postępować
+wobec innych w duchu braterstwa. Każdy człowiek posiada wszystkie prawa i
+wolności zawarte w niniejszej deklaracji bez względu na jakiekolwiek
+różnice rasy, koloru skóry, płci, języka, wyznania, poglądów politycznych
+i innych, narodowości, pochodzenia społecznego, majątku, urodzenia lub
+jakiegokolwiek innego stanu. Każdy człowiek ma prawo do życia, wolności i
+bezpieczeństwa swojej osoby. Dziś rano była ładna pogoda, więc
+spacerowaliśmy po starym mieście i zjedliśmy śniadanie w małej kawiarni
+nad rzeką. Czy chcesz przeczytać najnowsze wiadomości z miasta? Nasz
+sklep oferuje darmową dostawę wszystkich zamówień, a każdy produkt można
+zwrócić w ciągu trzydziestu dni. Co oni tam robili z dziećmi, które
+czekały na pociąg? To jest najważniejsza rzecz, jaką możemy zrobić,
+ponieważ ludzie potrzebują pomocy, kiedy mają kłopoty.`,
+
+	"sv": `Alla människor är födda fria och lika i värde och rättigheter. De har
+utrustats med förnuft och samvete och bör handla gentemot varandra i en
+anda av broderskap. Var och en är berättigad till alla de fri- och
+rättigheter som uttalas i denna förklaring utan åtskillnad av något slag,
+såsom ras, hudfärg, kön, språk, religion, politisk eller annan
+uppfattning, nationellt eller socialt ursprung, egendom, börd eller
+ställning i övrigt. Var och en har rätt till liv, frihet och personlig
+säkerhet. Vädret var fint i morse, så vi promenerade genom gamla stan och
+åt frukost på ett litet kafé vid ån. Vill du läsa de senaste nyheterna
+från staden? Vår butik erbjuder fri frakt på alla beställningar, och du
+kan returnera varje vara inom trettio dagar. Vad gjorde de där med barnen
+som väntade på tåget? Det är det viktigaste vi kan göra, eftersom
+människor behöver hjälp när de har problem och ingen annan lyssnar på dem.`,
+}
@@ -0,0 +1,126 @@
+// Package lang identifies the natural language of text with a character
+// trigram classifier whose profiles are bundled with the package.
+package lang
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MinLetters is the minimum number of letters of a text Detect
+// classifies.
+const MinLetters = 20
+
+// profile holds the normalized trigram frequencies of a language.
+type profile map[string]float64
+
+var profiles = make(map[string]profile)
+
+func init() {
+	for code, text := range corpus {
+		profiles[code] = newProfile(text)
+	}
+}
+
+// Languages returns the ISO 639-1 codes of the languages known to
+// Detect, sorted.
+func Languages() []string {
+	codes := make([]string, 0, len(profiles))
+	for code := range profiles {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Detect returns the ISO 639-1 code of the language of text and a
+// confidence between 0 and 1. If text has less than MinLetters letters
+// Detect returns an empty code.
+func Detect(text string) (code string, confidence float64) {
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < MinLetters {
+		return "", 0
+	}
+
+	p := newProfile(text)
+	var best, second float64
+	for _, c := range Languages() {
+		s := similarity(p, profiles[c])
+		switch {
+		case s > best:
+			code, best, second = c, s, best
+		case s > second:
+			second = s
+		}
+	}
+	if best == 0 {
+		return "", 0
+	}
+	return code, (best - second) / best
+}
+
+// Primary returns the lowercase primary language subtag of the BCP 47
+// language tag, for example "de" for "de-AT". It returns an empty string
+// for empty, wildcard and private-use tags.
+func Primary(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if len(tag) < 2 || len(tag) > 3 {
+		return ""
+	}
+	for _, r := range tag {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	return tag
+}
+
+// newProfile returns the trigram profile of the lowercased words of
+// text, each padded with a leading and trailing space.
+func newProfile(text string) profile {
+	p := make(profile)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		r := []rune(" " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			p[string(r[i:i+3])]++
+		}
+	}
+
+	// weight counts sublinearly, so frequent trigrams of short words
+	// shared by many languages do not dominate
+	var norm float64
+	for t, n := range p {
+		p[t] = 1 + math.Log(n)
+		norm += p[t] * p[t]
+	}
+	norm = math.Sqrt(norm)
+	for t := range p {
+		p[t] /= norm
+	}
+	return p
+}
+
+// similarity returns the cosine similarity of the profiles a and b.
+func similarity(a, b profile) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var s float64
+	for t, n := range a {
+		s += n * b[t]
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package lang
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		text string
+		want string
+	}{
+		{"The quick brown fox jumps over the lazy dog while the children are playing in the garden.", "en"},
+		{"Der schnelle braune Fuchs springt über den faulen Hund, während die Kinder im Garten spielen.", "de"},
+		{"Le renard brun rapide saute par-dessus le chien paresseux pendant que les enfants jouent dans le jardin.", "fr"},
+		{"El rápido zorro marrón salta sobre el perro perezoso mientras los niños juegan en el jardín.", "es"},
+		{"La volpe marrone veloce salta sopra il cane pigro mentre i bambini giocano nel giardino.", "it"},
+		{"De snelle bruine vos springt over de luie hond terwijl de kinderen in de tuin spelen.", "nl"},
+		{"A rápida raposa castanha salta sobre o cão preguiçoso enquanto as crianças brincam no jardim.", "pt"},
+		{"Szybki brązowy lis przeskakuje nad leniwym psem, podczas gdy dzieci bawią się w ogrodzie.", "pl"},
+		{"Den snabba bruna räven hoppar över den lata hunden medan barnen leker i trädgården.", "sv"},
+	}
+	for _, c := range testCases {
+		code, confidence := Detect(c.text)
+		if code != c.want {
+			t.Errorf("detect %q: expected %s, got %s", c.text, c.want, code)
+		}
+		if confidence <= 0 || confidence > 1 {
+			t.Errorf("detect %q: unexpected confidence %v", c.text, confidence)
+		}
+	}
+
+	if code, _ := Detect("Hello, world! 123"); code != "" {
+		t.Fatalf("detect: expected no language for short text, got %s", code)
+	}
+	if code, _ := Detect("12345 67890 !!! ??? ... 12345 67890 !!! ??? ..."); code != "" {
+		t.Fatalf("detect: expected no language without letters, got %s", code)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	want := []string{"de", "en", "es", "fr", "it", "nl", "pl", "pt", "sv"}
+	if got := Languages(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("languages: expected %v, got %v", want, got)
+	}
+}
+
+func TestPrimary(t *testing.T) {
+	for tag, want := range map[string]string{
+		"de":         "de",
+		"de-AT":      "de",
+		" EN_us ":    "en",
+		"gsw":        "gsw",
+		"":           "",
+		"*":          "",
+		"x-klingon":  "",
+		"x-default":  "",
+		"english":    "",
+		"zh-Hant-TW": "zh",
+	} {
+		if got := Primary(tag); got != want {
+			t.Errorf("primary %q: expected %q, got %q", tag, want, got)
+		}
+	}
+}
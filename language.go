@@ -0,0 +1,143 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mars9/crawler/lang"
+	"golang.org/x/net/html"
+)
+
+// maxLanguageText limits the text passed to the language classifier.
+const maxLanguageText = 10000
+
+// Weights of the language signals of a page. The classifier is weighted
+// by its confidence, so a confident classification of the text outvotes
+// a template-wide html lang attribute, but a single declared language
+// wins over an uncertain one.
+const (
+	weightHTMLLang        = 1.0
+	weightContentLanguage = 1.0
+	weightHreflang        = 1.5
+	weightClassifier      = 2.5
+)
+
+// detectLanguage returns the primary language subtag of the page node
+// fetched from final with the response header, and the hreflang
+// alternates of the page. The language is voted from the html lang
+// attribute, a Content-Language header naming a single language, the
+// hreflang link referring to the page itself and the text classifier of
+// package lang. It returns an empty language if there is no signal.
+func detectLanguage(final *url.URL, header http.Header, node *html.Node) (string, map[string]string) {
+	votes := make(map[string]float64)
+
+	if n := find(node, func(n *html.Node) bool { return n.Data == "html" }); n != nil {
+		if l := lang.Primary(attr(n, "lang")); l != "" {
+			votes[l] += weightHTMLLang
+		}
+	}
+	if v := header.Get("Content-Language"); v != "" && !strings.Contains(v, ",") {
+		if l := lang.Primary(v); l != "" {
+			votes[l] += weightContentLanguage
+		}
+	}
+
+	alternates := hreflang(final, node)
+	self := urlKey(final)
+	for tag, href := range alternates {
+		u, err := url.Parse(href)
+		if err != nil || urlKey(u) != self {
+			continue
+		}
+		if l := lang.Primary(tag); l != "" {
+			votes[l] += weightHreflang
+		}
+	}
+
+	if body := find(node, func(n *html.Node) bool { return n.Data == "body" }); body != nil {
+		s := text(body)
+		if len(s) > maxLanguageText {
+			s = s[:maxLanguageText]
+		}
+		if l, confidence := lang.Detect(s); l != "" {
+			votes[l] += weightClassifier * confidence
+		}
+	}
+
+	var language string
+	var best float64
+	for l, v := range votes {
+		if v > best || (v == best && l < language) {
+			language, best = l, v
+		}
+	}
+	return language, alternates
+}
+
+// hreflang returns the alternates of node declared by link elements
+// with the alternate relation and an hreflang attribute, keyed by the
+// lowercase language tag and resolved against parent. The x-default
+// alternate is included.
+func hreflang(parent *url.URL, node *html.Node) map[string]string {
+	var alternates map[string]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			tag := strings.ToLower(strings.TrimSpace(attr(n, "hreflang")))
+			href := attr(n, "href")
+			alternate := false
+			for _, r := range strings.Fields(attr(n, "rel")) {
+				alternate = alternate || strings.EqualFold(r, "alternate")
+			}
+			if alternate && tag != "" && href != "" {
+				if u, err := normalize(parent, href); err == nil {
+					if alternates == nil {
+						alternates = make(map[string]string)
+					}
+					alternates[tag] = u.String()
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return alternates
+}
+
+// acceptLanguage reports whether pages in language are processed. Pages
+// of unknown language are always accepted.
+func (w *Worker) acceptLanguage(language string) bool {
+	if len(w.Languages) == 0 || language == "" {
+		return true
+	}
+	for _, l := range w.Languages {
+		if lang.Primary(l) == language {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueAlternates enqueues the alternates of the page parent in an
+// accepted language, in the order of their tags.
+func (w *worker) enqueueAlternates(parent *url.URL, alternates map[string]string) {
+	tags := make([]string, 0, len(alternates))
+	for tag := range alternates {
+		if l := lang.Primary(tag); l != "" && w.w.acceptLanguage(l) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		if w.limitReached || w.closed {
+			return
+		}
+		if u, err := url.Parse(alternates[tag]); err == nil {
+			w.enqueue(parent, u, w.pusher)
+		}
+	}
+}
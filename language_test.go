@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	englishText = `<p>The weather was fine this morning, so we walked through the old town
+and had breakfast at a small cafe near the river.</p>`
+	germanText = `<p>Das Wetter war heute Morgen schön, deshalb sind wir durch die
+Altstadt gelaufen und haben in einem kleinen Café am Fluss gefrühstückt.</p>`
+)
+
+func TestDetectLanguage(t *testing.T) {
+	alternates := `<link rel="alternate" hreflang="en" href="/">` +
+		`<link rel="alternate" hreflang="de-DE" href="/de/">` +
+		`<link rel="alternate" hreflang="x-default" href="/">`
+	testCases := []struct {
+		page   string
+		header string
+		url    string
+		want   string
+	}{
+		{`<html><body>` + germanText + `</body></html>`, "", "/", "de"},
+		{`<html lang="en-US"><body>` + germanText + `</body></html>`, "", "/", "de"},
+		{`<html lang="en-US"><body><p>Hallo Welt</p></body></html>`, "", "/", "en"},
+		{`<html><body><p>Hallo Welt</p></body></html>`, "fr", "/", "fr"},
+		{`<html><body><p>Hallo Welt</p></body></html>`, "fr, de", "/", ""},
+		{`<html><head>` + alternates + `</head><body><p>Hallo</p></body></html>`, "", "/de/", "de"},
+		{`<html lang="en"><head>` + alternates + `</head><body><p>Hallo</p></body></html>`, "", "/de/", "de"},
+		{`<html><head>` + alternates + `</head><body>` + englishText + `</body></html>`, "", "/", "en"},
+		{`<html><body></body></html>`, "", "/", ""},
+	}
+	for i, c := range testCases {
+		node, err := html.Parse(strings.NewReader(c.page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := http.Header{}
+		if c.header != "" {
+			header.Set("Content-Language", c.header)
+		}
+		got, _ := detectLanguage(mustParseURL("http://example.com"+c.url), header, node)
+		if got != c.want {
+			t.Errorf("language %d: expected %q, got %q", i, c.want, got)
+		}
+	}
+
+	node, _ := html.Parse(strings.NewReader(`<html><head>` + alternates + `</head></html>`))
+	_, got := detectLanguage(mustParseURL("http://example.com/"), http.Header{}, node)
+	want := map[string]string{
+		"en":        "http://example.com/",
+		"de-de":     "http://example.com/de/",
+		"x-default": "http://example.com/",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("language: expected alternates %v, got %v", want, got)
+	}
+}
+
+func TestCrawlerLanguages(t *testing.T) {
+	t.Parallel()
+
+	pages := map[string]string{
+		"/": `<html lang="en"><head>` +
+			`<link rel="alternate" hreflang="de" href="/de/">` +
+			`<link rel="alternate" hreflang="fr" href="/fr/">` +
+			`</head><body>` + englishText + `<a href="/en/more">more</a></body></html>`,
+		"/de/":     `<html lang="de"><body>` + germanText + `<a href="/de/mehr">mehr</a><a href="/en/more">more</a></body></html>`,
+		"/de/mehr": `<html><body>` + germanText + `</body></html>`,
+		"/en/more": `<html><body>` + englishText + `</body></html>`,
+		"/fr/":     `<html lang="fr"><body><p>Bonjour</p></body></html>`,
+	}
+
+	w := newTestWorker()
+	w.GetFunc = func(u *url.URL) (io.ReadCloser, error) {
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+		return ioutil.NopCloser(strings.NewReader(pages[path])), nil
+	}
+	w.Languages = []string{"de-AT"}
+	var mu sync.Mutex
+	var processed []string
+	w.PageFunc = func(page *Page) {
+		mu.Lock()
+		processed = append(processed, page.URL.Path+" "+page.Language)
+		mu.Unlock()
+	}
+
+	c := New(w, time.Millisecond*20, nil)
+	c.Start(nil, w.Host)
+	<-c.Done()
+
+	sort.Strings(processed)
+	want := []string{"/de/ de", "/de/mehr de"}
+	if !reflect.DeepEqual(processed, want) {
+		t.Fatalf("languages: expected processed pages %v, got %v", want, processed)
+	}
+}
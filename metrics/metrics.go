@@ -0,0 +1,232 @@
+// Package metrics exports crawler statistics in the Prometheus text
+// exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mars9/crawler"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry exports the statistics of registered crawlers. Each crawler
+// is labeled with the name it is registered with.
+type Registry struct {
+	mu       sync.Mutex
+	crawlers map[string]*crawler.Crawler
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{crawlers: make(map[string]*crawler.Crawler)}
+}
+
+// Register registers the crawler c as name, replacing any crawler
+// already registered as name.
+func (r *Registry) Register(name string, c *crawler.Crawler) {
+	r.mu.Lock()
+	r.crawlers[name] = c
+	r.mu.Unlock()
+}
+
+// Unregister removes the crawler registered as name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.crawlers, name)
+	r.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics of all registered crawlers.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.WriteTo(w)
+}
+
+// WriteTo writes the metrics of all registered crawlers to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.crawlers))
+	for name := range r.crawlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stats := make([]crawler.Stats, len(names))
+	for i, name := range names {
+		stats[i] = r.crawlers[name].Stats()
+	}
+	r.mu.Unlock()
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	for _, m := range metrics {
+		fmt.Fprintf(cw, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(cw, "# TYPE %s %s\n", m.name, m.typ)
+		for i, name := range names {
+			m.write(cw, m.name, label{"crawler", name}, &stats[i])
+		}
+	}
+	if err := cw.w.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+type metric struct {
+	name  string
+	typ   string
+	help  string
+	write func(w io.Writer, name string, l label, s *crawler.Stats)
+}
+
+var metrics = []metric{
+	{"crawler_urls_queued_total", "counter", "URLs pushed to the crawl queue.",
+		value(func(s *crawler.Stats) float64 { return float64(s.Queued) })},
+	{"crawler_queue_length", "gauge", "URLs waiting in the crawl queue.",
+		value(func(s *crawler.Stats) float64 { return float64(s.QueueLen) })},
+	{"crawler_urls_fetched_total", "counter", "Pages fetched and processed.",
+		value(func(s *crawler.Stats) float64 { return float64(s.Fetched) })},
+	{"crawler_urls_unchanged_total", "counter", "URLs not modified since the last crawl.",
+		value(func(s *crawler.Stats) float64 { return float64(s.Unchanged) })},
+	{"crawler_urls_failed_total", "counter", "URLs failed to fetch by reason.",
+		func(w io.Writer, name string, l label, s *crawler.Stats) {
+			keys := make([]string, 0, len(s.Failed))
+			for k := range s.Failed {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				sample(w, name, float64(s.Failed[k]), l, label{"reason", k})
+			}
+		}},
+	{"crawler_robots_rejected_total", "counter", "URLs rejected by robots.txt.",
+		value(func(s *crawler.Stats) float64 { return float64(s.Failed[crawler.FailRobots]) })},
+	{"crawler_responses_total", "counter", "HTTP responses by status code.",
+		func(w io.Writer, name string, l label, s *crawler.Stats) {
+			codes := make([]int, 0, len(s.Status))
+			for code := range s.Status {
+				codes = append(codes, code)
+			}
+			sort.Ints(codes)
+			for _, code := range codes {
+				sample(w, name, float64(s.Status[code]), l, label{"code", strconv.Itoa(code)})
+			}
+		}},
+	{"crawler_downloaded_bytes_total", "counter", "Bytes downloaded.",
+		value(func(s *crawler.Stats) float64 { return float64(s.Bytes) })},
+	{"crawler_duplicate_urls_total", "counter", "Links rejected as duplicate URLs.",
+		value(func(s *crawler.Stats) float64 { return float64(s.Duplicates) })},
+	{"crawler_rejected_urls_total", "counter", "Links rejected by the accept rules.",
+		value(func(s *crawler.Stats) float64 { return float64(s.Rejected) })},
+	{"crawler_retries_total", "counter", "Requests retried after a session expired or through another proxy.",
+		value(func(s *crawler.Stats) float64 { return float64(s.Retries) })},
+	{"crawler_fetch_duration_seconds", "histogram", "Time taken to fetch a URL.",
+		histogram(func(s *crawler.Stats) *crawler.Histogram { return &s.LatencyHist })},
+	{"crawler_response_size_bytes", "histogram", "Size of fetched responses.",
+		histogram(func(s *crawler.Stats) *crawler.Histogram { return &s.SizeHist })},
+	{"crawler_worker_busy", "gauge", "Whether a worker is fetching a URL.",
+		func(w io.Writer, name string, l label, s *crawler.Stats) {
+			for _, ws := range s.Workers {
+				var busy float64
+				if ws.Busy() {
+					busy = 1
+				}
+				sample(w, name, busy, l, label{"worker", strconv.Itoa(ws.ID)})
+			}
+		}},
+	{"crawler_worker_urls_total", "counter", "URLs handled by a worker.",
+		func(w io.Writer, name string, l label, s *crawler.Stats) {
+			for _, ws := range s.Workers {
+				sample(w, name, float64(ws.Done), l, label{"worker", strconv.Itoa(ws.ID)})
+			}
+		}},
+	{"crawler_proxy_requests_total", "counter", "Requests sent through a proxy.",
+		proxy(func(p *crawler.ProxyStats) float64 { return float64(p.Requests) })},
+	{"crawler_proxy_failures_total", "counter", "Failed requests sent through a proxy.",
+		proxy(func(p *crawler.ProxyStats) float64 { return float64(p.Failures) })},
+	{"crawler_proxy_healthy", "gauge", "Whether a proxy is in use.",
+		proxy(func(p *crawler.ProxyStats) float64 {
+			if p.Healthy {
+				return 1
+			}
+			return 0
+		})},
+}
+
+func value(fn func(*crawler.Stats) float64) func(io.Writer, string, label, *crawler.Stats) {
+	return func(w io.Writer, name string, l label, s *crawler.Stats) {
+		sample(w, name, fn(s), l)
+	}
+}
+
+func proxy(fn func(*crawler.ProxyStats) float64) func(io.Writer, string, label, *crawler.Stats) {
+	return func(w io.Writer, name string, l label, s *crawler.Stats) {
+		for i := range s.Proxies {
+			sample(w, name, fn(&s.Proxies[i]), l, label{"proxy", s.Proxies[i].URL})
+		}
+	}
+}
+
+func histogram(fn func(*crawler.Stats) *crawler.Histogram) func(io.Writer, string, label, *crawler.Stats) {
+	return func(w io.Writer, name string, l label, s *crawler.Stats) {
+		h := fn(s)
+		var n int64
+		for i, bound := range h.Bounds {
+			if i < len(h.Counts) {
+				n += h.Counts[i]
+			}
+			sample(w, name+"_bucket", float64(n), l, label{"le", format(bound)})
+		}
+		sample(w, name+"_bucket", float64(h.Count), l, label{"le", "+Inf"})
+		sample(w, name+"_sum", h.Sum, l)
+		sample(w, name+"_count", float64(h.Count), l)
+	}
+}
+
+type label struct {
+	name, value string
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func sample(w io.Writer, name string, v float64, labels ...label) {
+	io.WriteString(w, name)
+	if len(labels) > 0 {
+		io.WriteString(w, "{")
+		for i, l := range labels {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", l.name, escaper.Replace(l.value))
+		}
+		io.WriteString(w, "}")
+	}
+	fmt.Fprintf(w, " %s\n", format(v))
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
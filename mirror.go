@@ -0,0 +1,291 @@
+package crawler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Mirror saves fetched resources to a local directory tree mirroring the
+// URL path, similar to wget --mirror --convert-links. Links in saved HTML
+// documents pointing to saved resources are rewritten to the local
+// copies, other links are made absolute. Links to resources saved after
+// the document are rewritten by Convert.
+//
+// Mirror.Save can be called from Worker.ProcessFunc.
+type Mirror struct {
+	// Dir defines the root directory of the mirror. Every host is saved
+	// to its own subdirectory.
+	Dir string
+
+	mu    sync.Mutex
+	names map[string]string // url key to local name
+	files map[string]bool   // local file names in use
+	dirs  map[string]bool   // local directory names in use
+	saved map[string]bool   // url keys of saved resources
+	docs  map[string]string // local names of saved HTML documents to their url
+}
+
+// NewMirror returns a mirror saving resources below dir.
+func NewMirror(dir string) *Mirror {
+	return &Mirror{
+		Dir:   dir,
+		names: make(map[string]string),
+		files: make(map[string]bool),
+		dirs:  make(map[string]bool),
+		saved: make(map[string]bool),
+		docs:  make(map[string]string),
+	}
+}
+
+// Path returns the slash-separated name, relative to Dir, url is saved
+// to. The name of a URL does not change during the lifetime of m.
+func (m *Mirror) Path(url *url.URL) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.name(url)
+}
+
+// Save writes data fetched from url to the local mirror. If data is an
+// HTML document node is rendered instead, with links to saved resources
+// of the same host rewritten as relative links to their local copies
+// and other links made absolute.
+func (m *Mirror) Save(url *url.URL, node *html.Node, data []byte) error {
+	if !url.IsAbs() {
+		return ErrNotAbsoluteURL
+	}
+
+	m.mu.Lock()
+	key := url.Host + normalizeKey(url)
+	name := m.name(url)
+	doc := node != nil && isHTML(data)
+	if doc {
+		var err error
+		if data, err = m.rewrite(url, name, node, key); err != nil {
+			m.mu.Unlock()
+			return err
+		}
+	}
+	m.mu.Unlock()
+
+	file := filepath.Join(m.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.saved[key] = true
+	if doc {
+		m.docs[name] = url.String()
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// Convert rewrites the links of saved HTML documents to resources saved
+// after the document, like wget --convert-links does at the end of a
+// crawl. It should be called once the crawl is done.
+func (m *Mirror) Convert() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.docs))
+	for name := range m.docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		u, err := url.Parse(m.docs[name])
+		if err != nil {
+			continue
+		}
+		file := filepath.Join(m.Dir, filepath.FromSlash(name))
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		node, err := parseHTML(data)
+		if err != nil {
+			return &ParseError{err}
+		}
+
+		changed := false
+		var walk func(*html.Node)
+		walk = func(n *html.Node) {
+			if attr := linkAttr(n); attr != nil {
+				// links not rewritten by Save are absolute
+				if target, err := url.Parse(attr.Val); err == nil && target.IsAbs() {
+					if link, ok := m.link(u, name, attr.Val, ""); ok && link != attr.Val {
+						attr.Val = link
+						changed = true
+					}
+				}
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+		walk(node)
+		if !changed {
+			continue
+		}
+		buf := &strings.Builder{}
+		if err = html.Render(buf, node); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(file, []byte(buf.String()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewrite renders node with rewritten links. The node tree itself is
+// left untouched.
+func (m *Mirror) rewrite(parent *url.URL, name string, node *html.Node, self string) ([]byte, error) {
+	type change struct {
+		attr *html.Attribute
+		val  string
+	}
+	var changes []change
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if attr := linkAttr(n); attr != nil {
+			if val, ok := m.link(parent, name, attr.Val, self); ok {
+				changes = append(changes, change{attr, attr.Val})
+				attr.Val = val
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+
+	buf := &strings.Builder{}
+	err := html.Render(buf, node)
+	for i := range changes { // restore original links
+		changes[i].attr.Val = changes[i].val
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(buf.String()), nil
+}
+
+// link returns href, found in the document saved as name, as a link
+// relative to name if it refers to a saved resource of the same host or
+// to the document itself, identified by the url key self. Otherwise it
+// returns the absolute URL of href. m.mu must be held.
+func (m *Mirror) link(parent *url.URL, name, href, self string) (string, bool) {
+	url, err := normalize(parent, href)
+	if err != nil {
+		return "", false
+	}
+	key := url.Host + normalizeKey(url)
+	if url.Host != parent.Host || (url.Scheme != "http" && url.Scheme != "https") ||
+		(!m.saved[key] && key != self) {
+		return url.String(), true
+	}
+
+	target := m.name(url)
+	link := relative(path.Dir(name), target)
+	if len(url.Fragment) > 0 {
+		link += "#" + url.Fragment
+	}
+	return link, true
+}
+
+// name returns the local name of url. m.mu must be held.
+func (m *Mirror) name(url *url.URL) string {
+	key := url.Host + normalizeKey(url)
+	if name, found := m.names[key]; found {
+		return name
+	}
+
+	clean := path.Clean("/" + url.Path)
+	dirs := strings.Split(strings.Trim(clean, "/"), "/")
+	file := dirs[len(dirs)-1]
+	dirs = dirs[:len(dirs)-1]
+	if len(file) == 0 {
+		file = "index.html"
+	} else if len(path.Ext(file)) == 0 { // a directory index
+		dirs = append(dirs, file)
+		file = "index.html"
+	}
+	if len(url.RawQuery) > 0 {
+		ext := path.Ext(file)
+		file = strings.TrimSuffix(file, ext) + "@" + escapeName(url.RawQuery) + ext
+	}
+
+	dir := escapeName(url.Host)
+	for _, elem := range dirs {
+		next := path.Join(dir, elem)
+		for i := 1; m.files[next]; i++ { // collides with a file
+			next = path.Join(dir, fmt.Sprintf("%s-%d", elem, i))
+		}
+		dir = next
+	}
+
+	ext := path.Ext(file)
+	base := strings.TrimSuffix(file, ext)
+	name := path.Join(dir, file)
+	for i := 1; m.files[name] || m.dirs[name]; i++ { // name collision
+		name = path.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+	}
+
+	for ; dir != "."; dir = path.Dir(dir) {
+		m.dirs[dir] = true
+	}
+	m.files[name] = true
+	m.names[key] = name
+	return name
+}
+
+// relative returns the slash-separated path of target relative to the
+// directory dir.
+func relative(dir, target string) string {
+	from := strings.Split(dir, "/")
+	to := strings.Split(target, "/")
+
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	elems := make([]string, 0, len(from)-i+len(to)-i)
+	for range from[i:] {
+		elems = append(elems, "..")
+	}
+	elems = append(elems, to[i:]...)
+	return strings.Join(elems, "/")
+}
+
+// escapeName replaces characters not safe in file names.
+func escapeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case strings.ContainsRune(".-_=&+,", r):
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func isHTML(data []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(data), "text/html")
+}
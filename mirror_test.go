@@ -0,0 +1,189 @@
+package crawler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestMirrorPath(t *testing.T) {
+	m := NewMirror("")
+
+	for _, c := range []struct {
+		url  string
+		want string
+	}{
+		{"http://example.com", "example.com/index.html"},
+		{"http://example.com/", "example.com/index.html"},
+		{"http://example.com/site.html", "example.com/site.html"},
+		{"http://example.com/sub/", "example.com/sub/index.html"},
+		{"http://example.com/sub", "example.com/sub/index.html"},
+		{"http://example.com/a/../site.html", "example.com/site.html"},
+		{"http://example.com/search?q=go/lang", "example.com/search/index@q=go_lang.html"},
+		{"http://example.com/site.html?page=2", "example.com/site@page=2.html"},
+		{"http://example.com:8080/site.html", "example.com_8080/site.html"},
+
+		// name collisions
+		{"http://example.com/sub/index.html", "example.com/sub/index-1.html"},
+		{"http://example.com/site.html/x.html", "example.com/site.html-1/x.html"},
+	} {
+		u, _ := url.Parse(c.url)
+		if got := m.Path(u); got != c.want {
+			t.Errorf("mirror path %q: expected %q, got %q", c.url, c.want, got)
+		}
+	}
+}
+
+func TestMirrorSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror")
+	if err != nil {
+		t.Fatalf("mirror: create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := NewMirror(dir)
+	data := []byte(`<html><body>` +
+		`<a href="/sub/page.html#top">a</a>` +
+		`<a href="/other">b</a>` +
+		`<img src="http://example.com/img/logo.png">` +
+		`<a href="http://google.com/">c</a>` +
+		`</body></html>`)
+	node, err := parseHTML(data)
+	if err != nil {
+		t.Fatalf("mirror: parse html: %v", err)
+	}
+
+	logo, _ := url.Parse("http://example.com/img/logo.png")
+	if err = m.Save(logo, nil, []byte("\x89PNG\r\n\x1a\n")); err != nil {
+		t.Fatalf("mirror: save: %v", err)
+	}
+	u, _ := url.Parse("http://example.com/sub/index.html")
+	if err = m.Save(u, node, data); err != nil {
+		t.Fatalf("mirror: save: %v", err)
+	}
+
+	name := filepath.Join(dir, "example.com", "sub", "index.html")
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("mirror: read saved file: %v", err)
+	}
+	for _, want := range []string{
+		`href="http://example.com/sub/page.html#top"`,
+		`href="http://example.com/other"`,
+		`src="../img/logo.png"`,
+		`href="http://google.com/"`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("mirror: expected %s in %s", want, got)
+		}
+	}
+
+	a := node.FirstChild.LastChild.FirstChild
+	if href := linkAttr(a).Val; href != "/sub/page.html#top" {
+		t.Fatalf("mirror: expected unmodified node, got href %q", href)
+	}
+
+	// links to resources saved later are rewritten by Convert
+	page, _ := url.Parse("http://example.com/sub/page.html")
+	if err = m.Save(page, nil, []byte("<html><body></body></html>")); err != nil {
+		t.Fatalf("mirror: save: %v", err)
+	}
+	if err = m.Convert(); err != nil {
+		t.Fatalf("mirror: convert: %v", err)
+	}
+	if got, err = ioutil.ReadFile(name); err != nil {
+		t.Fatalf("mirror: read saved file: %v", err)
+	}
+	for _, want := range []string{
+		`href="page.html#top"`,
+		`href="http://example.com/other"`,
+		`src="../img/logo.png"`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("mirror: expected %s in converted %s", want, got)
+		}
+	}
+}
+
+func TestMirrorCrawl(t *testing.T) {
+	t.Parallel()
+
+	pages := map[string]string{
+		"/": `<html><head><link rel="stylesheet" href="/style.css"></head><body>` +
+			`<img src="logo.png"><a href="/a">a</a><a href="/private">p</a></body></html>`,
+		"/a":       `<html><body><a href="/">home</a><img src="/logo.png"></body></html>`,
+		"/private": `<html><body>private</body></html>`,
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		page, found := pages[req.URL.Path]
+		if !found {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer s.Close()
+
+	dir := t.TempDir()
+	m := NewMirror(dir)
+	w := newTestWorker()
+	w.GetFunc = nil
+	w.Concurrent = 2
+	w.Host = mustParseURL(s.URL)
+	w.Reject = []*regexp.Regexp{regexp.MustCompile("/private$")}
+	w.PageFunc = func(page *Page) {
+		if err := m.Save(page.URL, page.Node, page.Data); err != nil {
+			t.Errorf("mirror: save: %v", err)
+		}
+	}
+	c := New(w, time.Millisecond*50, nil)
+	c.Start(nil, mustParseURL(s.URL+"/"))
+	<-c.Done()
+	if err := m.Convert(); err != nil {
+		t.Fatalf("mirror: convert: %v", err)
+	}
+
+	// every rewritten link refers to a saved file
+	var rewritten int
+	for _, path := range []string{"/", "/a"} {
+		name := filepath.Join(dir, filepath.FromSlash(m.Path(mustParseURL(s.URL+path))))
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("mirror: read %s: %v", path, err)
+		}
+		node, err := parseHTML(data)
+		if err != nil {
+			t.Fatalf("mirror: parse %s: %v", path, err)
+		}
+		find(node, func(n *html.Node) bool {
+			attr := linkAttr(n)
+			if attr == nil {
+				return false
+			}
+			if u, err := url.Parse(attr.Val); err == nil && u.IsAbs() {
+				if u.Host == w.Host.Host && n.Data == "a" && u.Path != "/private" {
+					t.Errorf("mirror: expected link to saved page %s to be rewritten in %s", attr.Val, path)
+				}
+				return false
+			}
+			rewritten++
+			target := filepath.Join(filepath.Dir(name), filepath.FromSlash(strings.SplitN(attr.Val, "#", 2)[0]))
+			if _, err := os.Stat(target); err != nil {
+				t.Errorf("mirror: link %q of %s: %v", attr.Val, path, err)
+			}
+			return false
+		})
+	}
+	if rewritten != 2 {
+		t.Fatalf("mirror: expected 2 rewritten links, got %d", rewritten)
+	}
+}
@@ -0,0 +1,364 @@
+package crawler
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mars9/crawler/graph"
+)
+
+// testProxy is an in-process HTTP forward proxy recording the hosts it
+// forwarded requests to.
+type testProxy struct {
+	auth  string // required user:password, if not empty
+	mu    sync.Mutex
+	hosts map[string]int
+}
+
+func (p *testProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if len(p.auth) > 0 && req.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(p.auth)) {
+		http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+	p.mu.Lock()
+	p.hosts[req.URL.Host]++
+	p.mu.Unlock()
+
+	out, err := http.NewRequest(req.Method, req.URL.String(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out.Header = req.Header.Clone()
+	out.Header.Del("Proxy-Authorization")
+	resp, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func newTestProxy(auth string) (*testProxy, *httptest.Server) {
+	p := &testProxy{auth: auth, hosts: make(map[string]int)}
+	return p, httptest.NewServer(p)
+}
+
+// deadProxy returns the URL of a proxy refusing connections.
+func deadProxy(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("proxy: listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return "http://" + addr
+}
+
+// serveSOCKS5 serves unauthenticated SOCKS5 CONNECT requests on l.
+func serveSOCKS5(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			buf := make([]byte, 256)
+			// greeting: version, methods
+			if _, err := io.ReadFull(conn, buf[:2]); err != nil || buf[0] != 5 {
+				return
+			}
+			if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+				return
+			}
+			conn.Write([]byte{5, 0})
+
+			// request: version, command, reserved, address type
+			if _, err := io.ReadFull(conn, buf[:4]); err != nil || buf[1] != 1 {
+				return
+			}
+			var host string
+			switch buf[3] {
+			case 1:
+				io.ReadFull(conn, buf[:4])
+				host = net.IP(buf[:4]).String()
+			case 3:
+				io.ReadFull(conn, buf[:1])
+				n := int(buf[0])
+				io.ReadFull(conn, buf[:n])
+				host = string(buf[:n])
+			default:
+				return
+			}
+			io.ReadFull(conn, buf[:2])
+			port := binary.BigEndian.Uint16(buf[:2])
+
+			target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+			if err != nil {
+				conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+				return
+			}
+			defer target.Close()
+			conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+			go io.Copy(target, conn)
+			io.Copy(conn, target)
+		}()
+	}
+}
+
+func proxyTarget() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+}
+
+func TestProxyPool(t *testing.T) {
+	t.Parallel()
+
+	target := proxyTarget()
+	defer target.Close()
+	good, s := newTestProxy("")
+	defer s.Close()
+
+	pool, err := NewProxyPool(deadProxy(t), s.URL)
+	if err != nil {
+		t.Fatalf("proxy: %v", err)
+	}
+	pool.MaxFailures = 1
+	w := &Worker{Proxies: pool}
+	for i := 0; i < 4; i++ {
+		body, err := w.Get(mustParseURL(target.URL + "/"))
+		if err != nil {
+			t.Fatalf("proxy: get %d: %v", i, err)
+		}
+		body.Close()
+	}
+	if n := good.hosts[mustParseURL(target.URL).Host]; n != 4 {
+		t.Fatalf("proxy: expected 4 proxied requests, got %d", n)
+	}
+
+	stats := pool.Stats()
+	if dead := stats[0]; dead.Healthy || dead.Requests != 1 || dead.Failures != 1 || len(dead.Error) == 0 {
+		t.Fatalf("proxy: expected unhealthy dead proxy after one failure, got %+v", dead)
+	}
+	if st := stats[1]; !st.Healthy || st.Requests != 4 || st.Failures != 0 || st.Latency <= 0 {
+		t.Fatalf("proxy: unexpected stats of good proxy %+v", st)
+	}
+
+	// the dead proxy is probed again after Retry
+	pool.Retry = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	body, err := w.Get(mustParseURL(target.URL + "/"))
+	if err != nil {
+		t.Fatalf("proxy: get after retry: %v", err)
+	}
+	body.Close()
+	if dead := pool.Stats()[0]; dead.Requests != 2 || dead.Failures != 2 {
+		t.Fatalf("proxy: expected dead proxy to be probed, got %+v", dead)
+	}
+}
+
+func TestProxyPoolUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	target := proxyTarget()
+	defer target.Close()
+	_, s := newTestProxy("user:secret")
+	defer s.Close()
+
+	pool, err := NewProxyPool(s.URL, deadProxy(t))
+	if err != nil {
+		t.Fatalf("proxy: %v", err)
+	}
+	pool.MaxFailures = 2
+	w := &Worker{Proxies: pool}
+	for i := 0; i < 2; i++ {
+		if _, err := w.Get(mustParseURL(target.URL + "/")); err == nil || errors.Is(err, ErrNoProxy) {
+			t.Fatalf("proxy: get %d: expected proxy error, got %v", i, err)
+		}
+	}
+	if _, err := w.Get(mustParseURL(target.URL + "/")); !errors.Is(err, ErrNoProxy) {
+		t.Fatalf("proxy: expected %v, got %v", ErrNoProxy, err)
+	}
+	for i, st := range pool.Stats() {
+		if st.Healthy || st.Requests != 2 || st.Failures != 2 {
+			t.Fatalf("proxy: unexpected stats of proxy %d: %+v", i, st)
+		}
+	}
+	if st := pool.Stats()[0]; st.Error != "proxy "+s.URL+": 407 Proxy Authentication Required" {
+		t.Fatalf("proxy: unexpected error %q", st.Error)
+	}
+}
+
+func TestProxyPoolPerHost(t *testing.T) {
+	t.Parallel()
+
+	targets := []*httptest.Server{proxyTarget(), proxyTarget()}
+	defer targets[0].Close()
+	defer targets[1].Close()
+	a, sa := newTestProxy("user:secret")
+	defer sa.Close()
+	b, sb := newTestProxy("")
+	defer sb.Close()
+
+	u := mustParseURL(sa.URL)
+	u.User = url.UserPassword("user", "secret")
+	pool, err := NewProxyPool(u.String(), sb.URL)
+	if err != nil {
+		t.Fatalf("proxy: %v", err)
+	}
+	pool.PerHost = true
+	w := &Worker{Proxies: pool}
+	for i := 0; i < 3; i++ {
+		for _, target := range targets {
+			body, err := w.Get(mustParseURL(target.URL + "/"))
+			if err != nil {
+				t.Fatalf("proxy: get %s: %v", target.URL, err)
+			}
+			body.Close()
+		}
+	}
+	if len(a.hosts) != 1 || len(b.hosts) != 1 {
+		t.Fatalf("proxy: expected one host per proxy, got %v and %v", a.hosts, b.hosts)
+	}
+	if a.hosts[mustParseURL(targets[0].URL).Host] != 3 || b.hosts[mustParseURL(targets[1].URL).Host] != 3 {
+		t.Fatalf("proxy: expected 3 requests per host, got %v and %v", a.hosts, b.hosts)
+	}
+	if st := pool.Stats()[0]; st.URL != "http://user:xxxxx@"+u.Host {
+		t.Fatalf("proxy: expected redacted URL, got %q", st.URL)
+	}
+}
+
+func TestProxyPoolSOCKS5(t *testing.T) {
+	t.Parallel()
+
+	target := proxyTarget()
+	defer target.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("proxy: listen: %v", err)
+	}
+	defer l.Close()
+	go serveSOCKS5(l)
+
+	pool, err := NewProxyPool("socks5://" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("proxy: %v", err)
+	}
+	w := newTestWorker()
+	w.GetFunc = nil
+	w.Host = mustParseURL(target.URL)
+	w.Proxies = pool
+	c := New(w, time.Millisecond*50, nil)
+	c.Start(nil, mustParseURL(target.URL+"/"))
+	<-c.Done()
+
+	stats := c.Stats()
+	if stats.Fetched != 1 {
+		t.Fatalf("proxy: expected 1 fetched page, got %d (%v)", stats.Fetched, stats.Proxies)
+	}
+	if len(stats.Proxies) != 1 || stats.Proxies[0].Requests != 1 || !stats.Proxies[0].Healthy {
+		t.Fatalf("proxy: unexpected proxy stats %+v", stats.Proxies)
+	}
+}
+
+func TestProxyPoolRetries(t *testing.T) {
+	t.Parallel()
+
+	target := proxyTarget()
+	defer target.Close()
+	_, s := newTestProxy("")
+	defer s.Close()
+
+	pool, err := NewProxyPool(deadProxy(t), s.URL)
+	if err != nil {
+		t.Fatalf("proxy: %v", err)
+	}
+	pool.MaxFailures = 1
+	w := newTestWorker()
+	w.GetFunc = nil
+	w.Host = mustParseURL(target.URL)
+	w.Proxies = pool
+	c := New(w, time.Millisecond*50, nil)
+	var retries []Event
+	c.Subscribe(ObserverFunc(func(e Event) {
+		if e.Kind == EventRetry {
+			retries = append(retries, e)
+		}
+	}))
+	c.Start(nil, mustParseURL(target.URL+"/"))
+	<-c.Done()
+
+	if stats := c.Stats(); stats.Fetched != 1 || stats.Retries != 1 {
+		t.Fatalf("proxy: expected 1 fetched page and 1 retry, got %d and %d", stats.Fetched, stats.Retries)
+	}
+	if len(retries) != 1 || retries[0].URL.String() != target.URL+"/" || retries[0].Reason == nil {
+		t.Fatalf("proxy: unexpected retry events %+v", retries)
+	}
+}
+
+func TestProxyPoolRequests(t *testing.T) {
+	t.Parallel()
+
+	var target *httptest.Server
+	target = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/sitemap.xml" {
+			w.Write([]byte(`<urlset><url><loc>` + target.URL + `/a</loc></url></urlset>`))
+			return
+		}
+		w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer target.Close()
+	p, s := newTestProxy("")
+	defer s.Close()
+
+	pool, err := NewProxyPool(s.URL)
+	if err != nil {
+		t.Fatalf("proxy: %v", err)
+	}
+	w := newTestWorker()
+	w.GetFunc = nil
+	w.Host = mustParseURL(target.URL)
+	w.Proxies = pool
+	c := New(w, time.Millisecond*50, nil)
+	if err := c.Start(mustParseURL(target.URL + "/sitemap.xml")); err != nil {
+		t.Fatalf("proxy: start: %v", err)
+	}
+	<-c.Done()
+
+	g := graph.New()
+	g.Add(graph.Edge{Source: target.URL + "/a", Target: target.URL + "/b", Kind: "a"})
+	if r := w.Checker().Check(g); r.Checked != 1 || len(r.Broken) != 0 {
+		t.Fatalf("proxy: unexpected check report %+v", r)
+	}
+	// the sitemap, the page and the checked link
+	if n := p.hosts[w.Host.Host]; n != 3 {
+		t.Fatalf("proxy: expected 3 proxied requests, got %d", n)
+	}
+}
+
+func TestNewProxyPool(t *testing.T) {
+	for _, proxies := range [][]string{
+		nil,
+		{"ftp://proxy:21"},
+		{"http://"},
+		{"http://proxy:3128", "://"},
+	} {
+		if _, err := NewProxyPool(proxies...); err == nil {
+			t.Fatalf("proxy: expected error for %q", proxies)
+		}
+	}
+}
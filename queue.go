@@ -1,8 +1,10 @@
 package crawler
 
 import (
+	"container/heap"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +15,9 @@ func (e Error) Error() string { return string(e) }
 const (
 	ErrNotAbsoluteURL = Error("not an absolute url")
 	ErrRejectedURL    = Error("url rejected")
+	ErrNotModified    = Error("not modified")
+	ErrRobotsRejected = Error("rejected by robots.txt")
+	ErrRejectedLang   = Error("language rejected")
 
 	ErrQueueClosed  = Error("queue is shut down")
 	ErrDuplicateURL = Error("duplicate url")
@@ -31,18 +36,35 @@ type Queue struct {
 	timer *time.Timer
 	ttl   time.Duration
 
-	mu     sync.Mutex
-	closed bool
-	set    map[string]struct{}
-	limit  int64
-	done   int64
+	mu       sync.Mutex
+	closed   bool
+	set      map[string]struct{}
+	limit    int64
+	done     int64
+	requeued int64
+	pending  int64 // queued URLs not yet sent to pop, accessed atomically
+
+	pmu      sync.Mutex // guards priority, Push blocks while holding mu
+	priority func(*url.URL) float64
 }
 
+// NewQueue returns a queue which closes itself if no URL was pushed for
+// ttl.
 func NewQueue(limit int64, ttl time.Duration) *Queue {
+	return newQueue(limit, ttl, time.NewTimer(ttl))
+}
+
+// NewRecrawlQueue returns a queue which never times out and is only
+// closed by Close, as used in recrawl mode.
+func NewRecrawlQueue(limit int64) *Queue {
+	return newQueue(limit, 0, nil)
+}
+
+func newQueue(limit int64, ttl time.Duration, timer *time.Timer) *Queue {
 	q := &Queue{
 		push:  make(chan *url.URL, 64), // queue channel capacity
 		pop:   make(chan *url.URL, 64), // queue channel capacity
-		timer: time.NewTimer(ttl),
+		timer: timer,
 		ttl:   ttl,
 		set:   make(map[string]struct{}),
 		limit: limit,
@@ -83,6 +105,25 @@ func (q *Queue) Push(url *url.URL) error {
 	return nil
 }
 
+// Requeue pushes an already visited URL again. The URL is neither
+// checked for duplicates nor counted against the queue limit.
+func (q *Queue) Requeue(url *url.URL) error {
+	if url == nil {
+		return ErrEmptyURL
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	q.set[normalizeKey(url)] = struct{}{}
+	q.requeued++
+	q.push <- url
+	q.mu.Unlock()
+	return nil
+}
+
 func (q *Queue) Close() error {
 	q.mu.Lock()
 	if q.closed {
@@ -95,31 +136,77 @@ func (q *Queue) Close() error {
 	return nil
 }
 
+// SetPriority sets the function computing the priority of pushed URLs.
+// URLs with a higher priority are popped first, URLs of equal priority in
+// the order they were pushed. Note that up to 64 URLs are buffered for
+// Pop in the order they were chosen.
+func (q *Queue) SetPriority(fn func(*url.URL) float64) {
+	q.pmu.Lock()
+	q.priority = fn
+	q.pmu.Unlock()
+}
+
+func (q *Queue) priorityOf(url *url.URL) float64 {
+	q.pmu.Lock()
+	fn := q.priority
+	q.pmu.Unlock()
+	if fn == nil {
+		return 0
+	}
+	return fn(url)
+}
+
 func (q *Queue) Pop() <-chan *url.URL {
 	return q.pop
 }
 
+// Len returns the number of URLs waiting in the queue.
+func (q *Queue) Len() int {
+	return int(atomic.LoadInt64(&q.pending)) + len(q.push) + len(q.pop)
+}
+
+// Enqueued returns the number of URLs pushed or requeued so far.
+func (q *Queue) Enqueued() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.done + q.requeued
+}
+
+func (q *Queue) timeout() <-chan time.Time {
+	if q.timer == nil {
+		return nil
+	}
+	return q.timer.C
+}
+
+func (q *Queue) reset() {
+	if q.timer != nil {
+		q.timer.Reset(q.ttl)
+	}
+}
+
 func (q *Queue) run(capacity int) {
-	queue := make([]*url.URL, 0, capacity)
+	queue := &urlHeap{items: make([]urlItem, 0, capacity)}
 	defer func() {
-		for len(queue) > 0 {
-			q.pop <- queue[0]
-			queue = queue[1:]
+		for queue.Len() > 0 {
+			q.pop <- heap.Pop(queue).(urlItem).url
+			atomic.AddInt64(&q.pending, -1)
 		}
 		close(q.pop)
 	}()
 
 	for {
-		if len(queue) == 0 {
+		if queue.Len() == 0 {
 			select {
 			case url, ok := <-q.push:
 				if !ok {
 					q.Close()
 					return
 				}
-				queue = append(queue, url)
-				q.timer.Reset(q.ttl)
-			case <-q.timer.C:
+				queue.push(url, q.priorityOf(url))
+				atomic.AddInt64(&q.pending, 1)
+				q.reset()
+			case <-q.timeout():
 				q.Close()
 				return
 			}
@@ -131,13 +218,53 @@ func (q *Queue) run(capacity int) {
 				q.Close()
 				return
 			}
-			queue = append(queue, url)
-			q.timer.Reset(q.ttl)
-		case q.pop <- queue[0]:
-			queue = queue[1:]
-		case <-q.timer.C:
+			queue.push(url, q.priorityOf(url))
+			atomic.AddInt64(&q.pending, 1)
+			q.reset()
+		case q.pop <- queue.items[0].url:
+			heap.Pop(queue)
+			atomic.AddInt64(&q.pending, -1)
+		case <-q.timeout():
 			q.Close()
 			return
 		}
 	}
 }
+
+type urlItem struct {
+	url      *url.URL
+	priority float64
+	seq      int64
+}
+
+// urlHeap orders URLs by decreasing priority and in FIFO order for
+// equal priorities.
+type urlHeap struct {
+	items []urlItem
+	seq   int64
+}
+
+func (h *urlHeap) push(url *url.URL, priority float64) {
+	h.seq++
+	heap.Push(h, urlItem{url, priority, h.seq})
+}
+
+func (h *urlHeap) Len() int { return len(h.items) }
+
+func (h *urlHeap) Less(i, j int) bool {
+	if h.items[i].priority != h.items[j].priority {
+		return h.items[i].priority > h.items[j].priority
+	}
+	return h.items[i].seq < h.items[j].seq
+}
+
+func (h *urlHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *urlHeap) Push(x interface{}) { h.items = append(h.items, x.(urlItem)) }
+
+func (h *urlHeap) Pop() interface{} {
+	item := h.items[len(h.items)-1]
+	h.items[len(h.items)-1] = urlItem{}
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
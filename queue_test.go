@@ -3,6 +3,7 @@ package crawler
 import (
 	"fmt"
 	"net/url"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -105,3 +106,79 @@ func TestQueueClose(t *testing.T) {
 		t.Fatalf("close queue: expected closed pop channel")
 	}
 }
+
+func TestQueueZeroTTL(t *testing.T) {
+	q := NewQueue(0, 0) // closes at once
+	if _, ok := <-q.Pop(); ok {
+		t.Fatalf("queue: expected closed pop channel")
+	}
+	if err := q.Push(exampleURL); err != ErrQueueClosed {
+		t.Fatalf("send queue: expected %v error, got %v", ErrQueueClosed, err)
+	}
+}
+
+func TestQueueRequeue(t *testing.T) {
+	q := NewRecrawlQueue(1)
+
+	if err := q.Push(exampleURL); err != nil {
+		t.Fatalf("send queue: expected <nil> error, got %v", err)
+	}
+	if err := q.Push(exampleURL); err != ErrDuplicateURL {
+		t.Fatalf("send queue: expected %v error, got %v", ErrDuplicateURL, err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := q.Requeue(exampleURL); err != nil {
+			t.Fatalf("requeue: expected <nil> error, got %v", err)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		if got := <-q.Pop(); got != exampleURL {
+			t.Fatalf("queue: expected %q, got %q", exampleURL, got)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("close queue: expected <nil> error, got %v", err)
+	}
+	if err := q.Requeue(exampleURL); err != ErrQueueClosed {
+		t.Fatalf("requeue: expected %v error, got %v", ErrQueueClosed, err)
+	}
+}
+
+func TestQueuePriority(t *testing.T) {
+	q := NewQueue(0, time.Minute)
+	q.SetPriority(RankPriority(map[string]float64{
+		"https://golang.org/page100": 2,
+		"https://golang.org/page150": 1,
+	}))
+
+	n := 200
+	for i := 0; i < n; i++ {
+		u, _ := url.Parse(fmt.Sprintf("https://golang.org/page%d", i))
+		if err := q.Push(u); err != nil {
+			t.Fatalf("send url: %v", err)
+		}
+	}
+	for atomic.LoadInt64(&q.pending) != int64(n-cap(q.pop)) {
+		time.Sleep(time.Millisecond)
+	}
+	q.Close()
+
+	var got []string
+	for u := range q.Pop() {
+		got = append(got, u.Path)
+	}
+	if len(got) != n {
+		t.Fatalf("queue: expected %d results, got %d", n, len(got))
+	}
+
+	want := []string{"/page0", "/page63", "/page100", "/page150", "/page64", "/page65"}
+	for i, j := range []int{0, 63, 64, 65, 66, 67} {
+		if got[j] != want[i] {
+			t.Fatalf("queue: expected %q at %d, got %q", want[i], j, got[j])
+		}
+	}
+	if got[n-1] != "/page199" {
+		t.Fatalf("queue: expected last url /page199, got %q", got[n-1])
+	}
+}
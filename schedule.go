@@ -0,0 +1,207 @@
+package crawler
+
+import (
+	"container/heap"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval    = 24 * time.Hour
+	DefaultMinInterval = time.Hour
+	DefaultMaxInterval = 30 * 24 * time.Hour
+)
+
+// Schedule keeps a recrawl schedule per URL. The recrawl interval of a
+// URL starts from its sitemap changefreq and lastmod and adapts to how
+// often the content of the URL actually changes: the interval halves if
+// the content changed since the last fetch and grows by half otherwise.
+//
+// A Worker with a Schedule runs in recrawl mode: its Crawler re-enqueues
+// URLs as they fall due instead of terminating on queue TTL.
+type Schedule struct {
+	// Interval defines the initial recrawl interval of URLs without a
+	// sitemap changefreq.
+	Interval time.Duration
+
+	// MinInterval and MaxInterval bound the recrawl interval of a URL.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+
+	mu    sync.Mutex
+	set   map[string]*entry
+	queue entryHeap
+	now   func() time.Time
+}
+
+// NewSchedule returns an empty schedule using the default intervals.
+func NewSchedule() *Schedule {
+	return &Schedule{
+		Interval:    DefaultInterval,
+		MinInterval: DefaultMinInterval,
+		MaxInterval: DefaultMaxInterval,
+		set:         make(map[string]*entry),
+		now:         time.Now,
+	}
+}
+
+// Prior sets the initial recrawl interval of url from its sitemap
+// changefreq and lastmod. A zero freq or lastmod is ignored. Prior has no
+// effect on URLs which were already fetched.
+func (s *Schedule) Prior(url *url.URL, freq time.Duration, lastmod time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(url)
+	if e.fetched {
+		return
+	}
+	if freq > 0 {
+		e.interval = s.clamp(freq)
+	}
+	if !lastmod.IsZero() {
+		// A page unchanged for longer than its interval changes less
+		// often than announced.
+		if age := s.clock().Sub(lastmod); age > e.interval {
+			e.interval = s.clamp(e.interval/2 + age/2)
+		}
+	}
+}
+
+// Observe records that url was fetched with content hash and schedules
+// the next fetch.
+func (s *Schedule) Observe(url *url.URL, hash uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(url)
+	if e.fetched {
+		if e.hash != hash {
+			e.interval = s.clamp(e.interval / 2)
+		} else {
+			e.interval = s.clamp(e.interval + e.interval/2)
+		}
+	}
+	e.hash = hash
+	s.fetched(e)
+}
+
+// Unchanged records that url was fetched and did not change since the
+// last fetch.
+func (s *Schedule) Unchanged(url *url.URL) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(url)
+	if e.fetched {
+		e.interval = s.clamp(e.interval + e.interval/2)
+	}
+	s.fetched(e)
+}
+
+// Due returns all fetched URLs whose recrawl time is not after now. The
+// returned URLs are rescheduled one interval ahead until they are
+// observed again.
+func (s *Schedule) Due(now time.Time) []*url.URL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*url.URL
+	for len(s.queue) > 0 && !s.queue[0].next.After(now) {
+		e := s.queue[0]
+		due = append(due, e.url)
+		e.next = now.Add(e.interval)
+		heap.Fix(&s.queue, 0)
+	}
+	return due
+}
+
+// Next returns the earliest recrawl time of all fetched URLs. It returns
+// false if no URL was fetched yet.
+func (s *Schedule) Next() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return time.Time{}, false
+	}
+	return s.queue[0].next, true
+}
+
+func (s *Schedule) entry(url *url.URL) *entry {
+	if s.set == nil {
+		s.set = make(map[string]*entry)
+	}
+	key := urlKey(url)
+	e, found := s.set[key]
+	if !found {
+		e = &entry{url: url, interval: s.clamp(s.Interval), index: -1}
+		s.set[key] = e
+	}
+	return e
+}
+
+func (s *Schedule) fetched(e *entry) {
+	e.fetched = true
+	e.next = s.clock().Add(e.interval)
+	if e.index < 0 {
+		heap.Push(&s.queue, e)
+	} else {
+		heap.Fix(&s.queue, e.index)
+	}
+}
+
+func (s *Schedule) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+func (s *Schedule) clamp(d time.Duration) time.Duration {
+	if d <= 0 {
+		d = DefaultInterval
+	}
+	if s.MinInterval > 0 && d < s.MinInterval {
+		d = s.MinInterval
+	}
+	if s.MaxInterval > 0 && d > s.MaxInterval {
+		d = s.MaxInterval
+	}
+	return d
+}
+
+type entry struct {
+	url      *url.URL
+	hash     uint64
+	fetched  bool
+	interval time.Duration
+	next     time.Time
+	index    int // heap index
+}
+
+type entryHeap []*entry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].next.Before(h[j].next) }
+
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	e.index = -1
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestScheduleAdapt(t *testing.T) {
+	now := time.Date(2016, 7, 16, 0, 0, 0, 0, time.UTC)
+	s := NewSchedule()
+	s.Interval = 8 * time.Hour
+	s.now = func() time.Time { return now }
+
+	u, _ := url.Parse("http://example.com/site1.html")
+	if due := s.Due(now.Add(time.Hour * 24 * 365)); len(due) != 0 {
+		t.Fatalf("schedule: expected no due urls before first fetch, got %d", len(due))
+	}
+
+	for i, c := range []struct {
+		hash uint64
+		want time.Duration
+	}{
+		{1, 8 * time.Hour},  // first fetch
+		{1, 12 * time.Hour}, // unchanged
+		{2, 6 * time.Hour},  // changed
+		{3, 3 * time.Hour},  // changed
+		{3, 270 * time.Minute},
+	} {
+		s.Observe(u, c.hash)
+		next, ok := s.Next()
+		if !ok {
+			t.Fatalf("schedule #%d: expected scheduled url", i)
+		}
+		if got := next.Sub(now); got != c.want {
+			t.Fatalf("schedule #%d: expected interval %v, got %v", i, c.want, got)
+		}
+	}
+
+	s.Unchanged(u)
+	next, _ := s.Next()
+	if got, want := next.Sub(now), 405*time.Minute; got != want {
+		t.Fatalf("schedule: expected interval %v, got %v", want, got)
+	}
+
+	if due := s.Due(next.Add(-time.Second)); len(due) != 0 {
+		t.Fatalf("schedule: expected no due urls, got %d", len(due))
+	}
+	if due := s.Due(next); len(due) != 1 || due[0] != u {
+		t.Fatalf("schedule: expected due url %q, got %v", u, due)
+	}
+	if due := s.Due(next); len(due) != 0 {
+		t.Fatalf("schedule: expected rescheduled url, got %v", due)
+	}
+}
+
+func TestSchedulePrior(t *testing.T) {
+	now := time.Date(2016, 7, 16, 0, 0, 0, 0, time.UTC)
+	s := NewSchedule()
+	s.now = func() time.Time { return now }
+
+	for _, c := range []struct {
+		freq    time.Duration
+		lastmod time.Time
+		want    time.Duration
+	}{
+		{0, time.Time{}, DefaultInterval},
+		{2 * time.Hour, time.Time{}, 2 * time.Hour},
+		{time.Second, time.Time{}, DefaultMinInterval},
+		{1<<63 - 1, time.Time{}, DefaultMaxInterval},
+		{2 * time.Hour, now.Add(-time.Hour), 2 * time.Hour},
+		{2 * time.Hour, now.Add(-10 * time.Hour), 6 * time.Hour},
+	} {
+		u, _ := url.Parse("http://example.com/" + c.want.String())
+		s.Prior(u, c.freq, c.lastmod)
+		s.Observe(u, 1)
+		if got := s.set[urlKey(u)].interval; got != c.want {
+			t.Fatalf("schedule prior %v %v: expected %v, got %v", c.freq, c.lastmod, c.want, got)
+		}
+		delete(s.set, urlKey(u))
+	}
+}
+
+func TestCrawlerRecrawl(t *testing.T) {
+	t.Parallel()
+
+	s := startETagServer(t)
+	defer s.Close()
+
+	mu := &sync.Mutex{}
+	fetched := map[string]int{}
+	done := make(chan struct{})
+	w := &Worker{
+		ProcessFunc: func(u *url.URL, _ *html.Node, _ []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			if fetched[u.Path]++; fetched[u.Path] == 3 && u.Path == "/a" {
+				close(done)
+			}
+		},
+		Schedule: &Schedule{
+			Interval:    time.Millisecond * 10,
+			MinInterval: time.Millisecond * 10,
+		},
+	}
+	w.Host, _ = url.Parse(s.URL + "/")
+
+	c := New(w, time.Millisecond, nil)
+	c.Start(nil, w.Host)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("recrawl: expected recrawled urls, got %v", fetched)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("recrawl: cannot close: %v", err)
+	}
+	<-c.Done()
+}
+
+func TestCrawlerRecrawlRejected(t *testing.T) {
+	t.Parallel()
+
+	done := make(chan struct{})
+	w := newTestWorker()
+	w.GetFunc = func(u *url.URL) (io.ReadCloser, error) {
+		defer close(done)
+		return ioutil.NopCloser(strings.NewReader(`<html lang="de"><body></body></html>`)), nil
+	}
+	w.Languages = []string{"en"}
+	w.Schedule = NewSchedule()
+
+	c := New(w, 0, nil)
+	c.Start(nil, w.Host)
+	<-done
+	if err := c.Close(); err != nil {
+		t.Fatalf("recrawl: cannot close: %v", err)
+	}
+	<-c.Done()
+
+	if next, ok := w.Schedule.Next(); ok {
+		t.Fatalf("recrawl: expected rejected page not scheduled, got %v", next)
+	}
+}
@@ -5,6 +5,7 @@ package sitemap
 import (
 	"encoding/xml"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -118,8 +119,12 @@ func Get(url, agent string) (*Sitemap, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	return Parse(resp.Body)
+}
 
-	data, err := ioutil.ReadAll(resp.Body)
+// Parse decodes the sitemap read from r.
+func Parse(r io.Reader) (*Sitemap, error) {
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
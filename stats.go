@@ -0,0 +1,291 @@
+package crawler
+
+import (
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Failure categories of Stats.Failed.
+const (
+	FailRobots   = "robots"   // rejected by robots.txt
+	FailStatus   = "status"   // non-200 response status
+	FailNetwork  = "network"  // connection or transfer error
+	FailParse    = "parse"    // page cannot be parsed
+	FailRejected = "rejected" // URL not allowed by the worker
+	FailOther    = "other"
+)
+
+// Stats is a snapshot of the statistics of a crawl.
+type Stats struct {
+	Queued    int64 // URLs pushed to the queue, including requeued URLs
+	QueueLen  int   // URLs waiting in the queue
+	Fetched   int64 // pages fetched with status 200 and processed
+	Unchanged int64 // URLs not modified since the last crawl
+	Bytes     int64 // bytes downloaded
+
+	// Failed counts failed URLs per failure category.
+	Failed map[string]int64
+
+	// Status counts responses per HTTP status code.
+	Status map[int]int64
+
+	Duplicates int64 // links rejected as duplicate URLs
+	Rejected   int64 // links rejected by the accept rules
+
+	// Retries counts requests retried after the session of Worker.Login
+	// expired or through another proxy of Worker.Proxies.
+	Retries int64
+
+	// Latency is the average time taken to fetch a URL.
+	Latency time.Duration
+
+	// LatencyHist counts fetch latencies in seconds, SizeHist counts
+	// response sizes in bytes.
+	LatencyHist Histogram
+	SizeHist    Histogram
+
+	Workers []WorkerStats
+
+	// Proxies holds the statistics of Worker.Proxies.
+	Proxies []ProxyStats
+}
+
+var (
+	// LatencyBuckets defines the upper bounds, in seconds, of the
+	// buckets of Stats.LatencyHist.
+	LatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// SizeBuckets defines the upper bounds, in bytes, of the buckets of
+	// Stats.SizeHist.
+	SizeBuckets = []float64{1 << 10, 1 << 12, 1 << 14, 1 << 16, 1 << 18, 1 << 20, 1 << 22, 1 << 24}
+)
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	// Bounds holds the inclusive upper bounds of the buckets in
+	// increasing order.
+	Bounds []float64
+
+	// Counts holds the number of observations per bucket. The last
+	// element counts the observations above all bounds.
+	Counts []int64
+
+	Sum   float64 // sum of all observations
+	Count int64   // number of observations
+}
+
+func newHistogram(bounds []float64) Histogram {
+	return Histogram{
+		Bounds: bounds,
+		Counts: make([]int64, len(bounds)+1),
+	}
+}
+
+func (h *Histogram) observe(v float64) {
+	i := 0
+	for i < len(h.Bounds) && v > h.Bounds[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Sum += v
+	h.Count++
+}
+
+func (h Histogram) clone() Histogram {
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+// WorkerStats describes the activity of a single crawler worker.
+type WorkerStats struct {
+	ID     int
+	Done   int64     // URLs handled by the worker
+	Failed int64     // URLs the worker failed to fetch
+	URL    string    // URL currently fetched, empty if idle
+	Since  time.Time // start of the current fetch or idle period
+}
+
+// Busy reports whether the worker is fetching a URL.
+func (w WorkerStats) Busy() bool { return len(w.URL) > 0 }
+
+// stats collects crawl statistics. All methods are safe to call on a
+// nil *stats.
+type stats struct {
+	mu         sync.Mutex
+	fetched    int64
+	unchanged  int64
+	bytes      int64
+	duplicates int64
+	rejected   int64
+	retries    int64
+	requests   int64
+	latency    time.Duration
+	failed     map[string]int64
+	status     map[int]int64
+	latencies  Histogram
+	sizes      Histogram
+	workers    []WorkerStats
+}
+
+func newStats(workers int) *stats {
+	s := &stats{
+		failed:    make(map[string]int64),
+		status:    make(map[int]int64),
+		latencies: newHistogram(LatencyBuckets),
+		sizes:     newHistogram(SizeBuckets),
+		workers:   make([]WorkerStats, workers),
+	}
+	now := time.Now()
+	for i := range s.workers {
+		s.workers[i] = WorkerStats{ID: i + 1, Since: now}
+	}
+	return s
+}
+
+func (s *stats) worker(id int) *WorkerStats {
+	if id < 1 || id > len(s.workers) {
+		return nil
+	}
+	return &s.workers[id-1]
+}
+
+func (s *stats) add(counter *int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	*counter++
+	s.mu.Unlock()
+}
+
+// processed counts a fetched page passed to the worker.
+func (s *stats) processed() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.fetched++
+	s.mu.Unlock()
+}
+
+// retried counts a retried request.
+func (s *stats) retried() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.retries++
+	s.mu.Unlock()
+}
+
+func (s *stats) begin(id int, url *url.URL) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if w := s.worker(id); w != nil {
+		w.URL = url.String()
+		w.Since = time.Now()
+	}
+	s.mu.Unlock()
+}
+
+func (s *stats) response(code, n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.status[code]++
+	s.bytes += int64(n)
+	if code == 304 {
+		s.unchanged++
+	} else {
+		s.sizes.observe(float64(n))
+	}
+	s.mu.Unlock()
+}
+
+func (s *stats) end(id int, latency time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	s.latency += latency
+	s.latencies.observe(latency.Seconds())
+	w := s.worker(id)
+	if w != nil {
+		w.Done++
+		w.URL = ""
+		w.Since = time.Now()
+	}
+
+	if err == nil {
+		return
+	}
+	if w != nil {
+		w.Failed++
+	}
+	if e, ok := err.(*StatusError); ok {
+		s.status[e.Code]++
+	}
+	s.failed[category(err)]++
+}
+
+func (s *stats) snapshot() Stats {
+	if s == nil {
+		return Stats{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{
+		Fetched:     s.fetched,
+		Unchanged:   s.unchanged,
+		Bytes:       s.bytes,
+		Duplicates:  s.duplicates,
+		Rejected:    s.rejected,
+		Retries:     s.retries,
+		Failed:      make(map[string]int64, len(s.failed)),
+		Status:      make(map[int]int64, len(s.status)),
+		Workers:     append([]WorkerStats(nil), s.workers...),
+		LatencyHist: s.latencies.clone(),
+		SizeHist:    s.sizes.clone(),
+	}
+	if s.requests > 0 {
+		st.Latency = s.latency / time.Duration(s.requests)
+	}
+	for k, v := range s.failed {
+		st.Failed[k] = v
+	}
+	for k, v := range s.status {
+		st.Status[k] = v
+	}
+	return st
+}
+
+// category returns the failure category of err.
+func category(err error) string {
+	switch err.(type) {
+	case *StatusError:
+		return FailStatus
+	case *ParseError:
+		return FailParse
+	case *url.Error, net.Error:
+		return FailNetwork
+	}
+	switch err {
+	case io.ErrUnexpectedEOF:
+		return FailNetwork
+	case ErrRobotsRejected:
+		return FailRobots
+	case ErrRejectedURL, ErrNotAbsoluteURL:
+		return FailRejected
+	}
+	return FailOther
+}
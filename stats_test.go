@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCrawlerStats(t *testing.T) {
+	t.Parallel()
+
+	pages := map[string]string{
+		"/":  `<html><body><a href="/a">a</a><a href="/404">404</a><a href="http://google.com">google</a></body></html>`,
+		"/a": `<html><body><a href="/">index</a></body></html>`,
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		page, found := pages[req.URL.Path]
+		if !found {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer s.Close()
+
+	w := &Worker{Concurrent: 2}
+	w.Host, _ = url.Parse(s.URL + "/")
+
+	c := New(w, time.Millisecond*20, nil)
+	c.Start(nil, w.Host)
+	<-c.Done()
+
+	got := c.Stats()
+	if got.Queued != 3 {
+		t.Fatalf("stats: expected 3 queued urls, got %d", got.Queued)
+	}
+	if got.QueueLen != 0 {
+		t.Fatalf("stats: expected empty queue, got %d", got.QueueLen)
+	}
+	if got.Fetched != 2 {
+		t.Fatalf("stats: expected 2 fetched urls, got %d", got.Fetched)
+	}
+	if got.Failed[FailStatus] != 1 {
+		t.Fatalf("stats: expected 1 status failure, got %v", got.Failed)
+	}
+	if got.Status[http.StatusOK] != 2 || got.Status[http.StatusNotFound] != 1 {
+		t.Fatalf("stats: unexpected status counts %v", got.Status)
+	}
+	if want := int64(len(pages["/"]) + len(pages["/a"])); got.Bytes != want {
+		t.Fatalf("stats: expected %d bytes, got %d", want, got.Bytes)
+	}
+	if got.Duplicates != 1 || got.Rejected != 1 {
+		t.Fatalf("stats: expected 1 duplicate and 1 rejected url, got %d and %d", got.Duplicates, got.Rejected)
+	}
+	if got.Latency <= 0 {
+		t.Fatalf("stats: expected positive latency, got %v", got.Latency)
+	}
+	if got.LatencyHist.Count != 3 || got.SizeHist.Count != 2 {
+		t.Fatalf("stats: expected 3 latencies and 2 sizes, got %d and %d",
+			got.LatencyHist.Count, got.SizeHist.Count)
+	}
+	if got.SizeHist.Counts[0] != 2 {
+		t.Fatalf("stats: expected 2 sizes in first bucket, got %v", got.SizeHist.Counts)
+	}
+
+	if len(got.Workers) != 2 {
+		t.Fatalf("stats: expected 2 workers, got %d", len(got.Workers))
+	}
+	var done int64
+	for _, w := range got.Workers {
+		if w.Busy() {
+			t.Fatalf("stats: expected idle worker #%d", w.ID)
+		}
+		done += w.Done
+	}
+	if done != 3 {
+		t.Fatalf("stats: expected 3 handled urls, got %d", done)
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		want string
+	}{
+		{&StatusError{404, "404 Not Found"}, FailStatus},
+		{&ParseError{ErrEmptyURL}, FailParse},
+		{&url.Error{Op: "Get", URL: "http://example.com", Err: ErrEmptyURL}, FailNetwork},
+		{ErrRobotsRejected, FailRobots},
+		{ErrRejectedURL, FailRejected},
+		{ErrEmptyURL, FailOther},
+	} {
+		if got := category(c.err); got != c.want {
+			t.Errorf("category %v: expected %q, got %q", c.err, c.want, got)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]float64{1, 2, 4})
+	for _, v := range []float64{0, 1, 1.5, 4, 5, 100} {
+		h.observe(v)
+	}
+	want := []int64{2, 1, 1, 2}
+	for i := range want {
+		if h.Counts[i] != want[i] {
+			t.Fatalf("histogram: expected counts %v, got %v", want, h.Counts)
+		}
+	}
+	if h.Count != 6 || h.Sum != 111.5 {
+		t.Fatalf("histogram: expected count 6 and sum 111.5, got %d and %v", h.Count, h.Sum)
+	}
+}
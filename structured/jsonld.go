@@ -0,0 +1,130 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// jsonld extracts the items of the JSON-LD script blocks of node.
+func (d *Data) jsonld(node *html.Node) {
+	walk(node, func(n *html.Node) bool {
+		if n.Data != "script" {
+			return true
+		}
+		typ, _ := attr(n, "type")
+		if i := strings.IndexByte(typ, ';'); i >= 0 {
+			typ = typ[:i]
+		}
+		if !strings.EqualFold(strings.TrimSpace(typ), "application/ld+json") {
+			return false
+		}
+
+		var data strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				data.WriteString(c.Data)
+			}
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(data.String()), &v); err != nil {
+			d.Errors = append(d.Errors, fmt.Errorf("json-ld: %v", err))
+			return false
+		}
+		d.Items = append(d.Items, jsonldItems("", v)...)
+		return false
+	})
+}
+
+// jsonldItems returns the top-level items of the JSON-LD value v with
+// the default vocabulary vocab.
+func jsonldItems(vocab string, v interface{}) []*Item {
+	switch v := v.(type) {
+	case []interface{}:
+		var items []*Item
+		for _, e := range v {
+			items = append(items, jsonldItems(vocab, e)...)
+		}
+		return items
+	case map[string]interface{}:
+		vocab = jsonldVocab(vocab, v["@context"])
+		if graph, found := v["@graph"]; found {
+			return jsonldItems(vocab, graph)
+		}
+		return []*Item{jsonldItem(vocab, v)}
+	}
+	return nil
+}
+
+// jsonldVocab returns the vocabulary defined by the JSON-LD context c,
+// or vocab if c does not define one.
+func jsonldVocab(vocab string, c interface{}) string {
+	switch c := c.(type) {
+	case string:
+		return c
+	case map[string]interface{}:
+		if v, ok := c["@vocab"].(string); ok {
+			return v
+		}
+	case []interface{}:
+		for _, e := range c {
+			vocab = jsonldVocab(vocab, e)
+		}
+	}
+	return vocab
+}
+
+func jsonldItem(vocab string, m map[string]interface{}) *Item {
+	vocab = jsonldVocab(vocab, m["@context"])
+	item := newItem(SourceJSONLD)
+	for key, value := range m {
+		switch {
+		case key == "@type":
+			for _, t := range list(value) {
+				if s, ok := t.(string); ok {
+					item.Type = append(item.Type, term(vocab, s))
+				}
+			}
+		case key == "@id":
+			item.ID, _ = value.(string)
+		case strings.HasPrefix(key, "@"):
+		default:
+			name := term(vocab, key)
+			for _, v := range list(value) {
+				if x := jsonldValue(vocab, v); x != nil {
+					item.add(name, x)
+				}
+			}
+		}
+	}
+	return item
+}
+
+func jsonldValue(vocab string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string, float64, bool:
+		return v
+	case map[string]interface{}:
+		if value, found := v["@value"]; found {
+			return jsonldValue(vocab, value)
+		}
+		return jsonldItem(vocab, v)
+	}
+	return nil
+}
+
+// list returns the elements of v if v is an array, and v otherwise.
+// Nested arrays are flattened.
+func list(v interface{}) []interface{} {
+	a, ok := v.([]interface{})
+	if !ok {
+		return []interface{}{v}
+	}
+	var values []interface{}
+	for _, e := range a {
+		values = append(values, list(e)...)
+	}
+	return values
+}
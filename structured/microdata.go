@@ -0,0 +1,113 @@
+package structured
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// microdata returns the top-level Microdata items of node.
+func microdata(base *url.URL, node *html.Node) []*Item {
+	ids := make(map[string]*html.Node)
+	walk(node, func(n *html.Node) bool {
+		if id, ok := attr(n, "id"); ok {
+			ids[id] = n
+		}
+		return true
+	})
+
+	var items []*Item
+	walk(node, func(n *html.Node) bool {
+		if has(n, "itemscope") && !has(n, "itemprop") {
+			items = append(items, microdataItem(base, n, ids, make(map[*html.Node]bool)))
+		}
+		return true
+	})
+	return items
+}
+
+// microdataItem returns the item of the item scope node. Path holds the
+// item scopes and referenced elements being resolved, which are skipped
+// to break itemref cycles.
+func microdataItem(base *url.URL, node *html.Node, ids map[string]*html.Node, path map[*html.Node]bool) *Item {
+	path[node] = true
+	defer delete(path, node)
+
+	item := newItem(SourceMicrodata)
+	types, _ := attr(node, "itemtype")
+	for _, t := range strings.Fields(types) {
+		item.Type = append(item.Type, term("", t))
+	}
+	if id, ok := attr(node, "itemid"); ok {
+		item.ID = resolve(base, id)
+	}
+
+	var props func(*html.Node)
+	prop := func(n *html.Node) {
+		if names, ok := attr(n, "itemprop"); ok && !(path[n] && has(n, "itemscope")) {
+			value := microdataValue(base, n, ids, path)
+			for _, name := range strings.Fields(names) {
+				item.add(term("", name), value)
+			}
+		}
+		if !has(n, "itemscope") {
+			props(n)
+		}
+	}
+	props = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				prop(c)
+			}
+		}
+	}
+	props(node)
+
+	refs, _ := attr(node, "itemref")
+	for _, ref := range strings.Fields(refs) {
+		if n, found := ids[ref]; found && !path[n] {
+			path[n] = true
+			prop(n)
+			delete(path, n)
+		}
+	}
+	return item
+}
+
+// microdataValue returns the property value of the element node.
+func microdataValue(base *url.URL, node *html.Node, ids map[string]*html.Node, path map[*html.Node]bool) interface{} {
+	if has(node, "itemscope") {
+		return microdataItem(base, node, ids, path)
+	}
+	if content, ok := attr(node, "content"); ok {
+		return strings.TrimSpace(content)
+	}
+
+	var key string
+	switch node.Data {
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		key = "src"
+	case "a", "area", "link":
+		key = "href"
+	case "object":
+		key = "data"
+	case "data", "meter":
+		v, _ := attr(node, "value")
+		return strings.TrimSpace(v)
+	case "time":
+		if v, ok := attr(node, "datetime"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	if len(key) > 0 {
+		v, _ := attr(node, key)
+		return resolve(base, v)
+	}
+	return text(node)
+}
+
+func has(node *html.Node, key string) bool {
+	_, ok := attr(node, key)
+	return ok
+}
@@ -0,0 +1,71 @@
+package structured
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// rdfa returns the top-level RDFa Lite items of node. Properties outside
+// of an element with a typeof attribute are ignored.
+func rdfa(base *url.URL, node *html.Node) []*Item {
+	var items []*Item
+	var visit func(n *html.Node, vocab string, parent *Item)
+	visit = func(n *html.Node, vocab string, parent *Item) {
+		if n.Type == html.ElementNode {
+			if v, ok := attr(n, "vocab"); ok {
+				vocab = strings.TrimSpace(v)
+			}
+
+			var item *Item
+			if types, ok := attr(n, "typeof"); ok {
+				item = newItem(SourceRDFa)
+				for _, t := range strings.Fields(types) {
+					item.Type = append(item.Type, term(vocab, t))
+				}
+				if r, ok := attr(n, "resource"); ok {
+					item.ID = resolve(base, r)
+				}
+			}
+
+			if names, ok := attr(n, "property"); ok && parent != nil {
+				var value interface{} = item
+				if item == nil {
+					value = rdfaValue(base, n)
+				}
+				for _, name := range strings.Fields(names) {
+					parent.add(term(vocab, name), value)
+				}
+			} else if item != nil {
+				items = append(items, item)
+			}
+			if item != nil {
+				parent = item
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c, vocab, parent)
+		}
+	}
+	visit(node, "", nil)
+	return items
+}
+
+// rdfaValue returns the property value of the element node.
+func rdfaValue(base *url.URL, node *html.Node) string {
+	if content, ok := attr(node, "content"); ok {
+		return strings.TrimSpace(content)
+	}
+	for _, key := range []string{"href", "src", "resource"} {
+		if v, ok := attr(node, key); ok {
+			return resolve(base, v)
+		}
+	}
+	if node.Data == "time" {
+		if v, ok := attr(node, "datetime"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return text(node)
+}
@@ -0,0 +1,194 @@
+package structured
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Product is the normalized metadata of a product.
+type Product struct {
+	Source       string   `json:"source"`
+	Name         string   `json:"name,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	Images       []string `json:"images,omitempty"`
+	SKU          string   `json:"sku,omitempty"`
+	Brand        string   `json:"brand,omitempty"`
+	Price        string   `json:"price,omitempty"`
+	Currency     string   `json:"currency,omitempty"`
+	Availability string   `json:"availability,omitempty"`
+}
+
+// Article is the normalized metadata of an article, such as a news
+// article or blog post.
+type Article struct {
+	Source      string   `json:"source"`
+	Headline    string   `json:"headline,omitempty"`
+	Description string   `json:"description,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Images      []string `json:"images,omitempty"`
+	Authors     []string `json:"authors,omitempty"`
+	Publisher   string   `json:"publisher,omitempty"`
+	Published   string   `json:"published,omitempty"` // date as given, such as 2016-07-16
+	Modified    string   `json:"modified,omitempty"`
+	Section     string   `json:"section,omitempty"`
+}
+
+// SourceOpenGraph is the source of records taken from OpenGraph
+// properties.
+const SourceOpenGraph = "opengraph"
+
+// articleTypes are the schema.org types of articles.
+var articleTypes = []string{"Article", "NewsArticle", "BlogPosting", "ScholarlyArticle", "TechArticle", "Report"}
+
+// Products returns the schema.org Product items of d as products. If d
+// has no Product item, a product is taken from the OpenGraph properties
+// of a page of og:type product.
+func (d *Data) Products() []Product {
+	var products []Product
+	for _, item := range d.Find("Product") {
+		p := Product{
+			Source:      item.Source,
+			Name:        value(item, "name"),
+			Description: value(item, "description"),
+			URL:         value(item, "url"),
+			Images:      values(item, "image", "url"),
+			SKU:         value(item, "sku"),
+			Brand:       name(item, "brand"),
+		}
+		if o := item.Item("offers"); o != nil {
+			if o.Is("AggregateOffer") && len(value(o, "price")) == 0 {
+				p.Price = value(o, "lowPrice")
+			} else {
+				p.Price = value(o, "price")
+			}
+			p.Currency = value(o, "priceCurrency")
+			p.Availability = term("", value(o, "availability"))
+		}
+		products = append(products, p)
+	}
+	if len(products) > 0 || !d.ogType("product") {
+		return products
+	}
+	return []Product{{
+		Source:       SourceOpenGraph,
+		Name:         d.og("og:title"),
+		Description:  d.og("og:description"),
+		URL:          d.og("og:url"),
+		Images:       d.OpenGraph["og:image"],
+		Brand:        d.og("product:brand"),
+		Price:        d.og("product:price:amount"),
+		Currency:     d.og("product:price:currency"),
+		Availability: d.og("product:availability"),
+	}}
+}
+
+// Articles returns the schema.org Article items of d, including subtypes
+// such as NewsArticle and BlogPosting, as articles. If d has no article
+// item, an article is taken from the OpenGraph properties of a page of
+// og:type article.
+func (d *Data) Articles() []Article {
+	var articles []Article
+	seen := make(map[*Item]bool)
+	for _, typ := range articleTypes {
+		for _, item := range d.Find(typ) {
+			if seen[item] {
+				continue
+			}
+			seen[item] = true
+			headline := value(item, "headline")
+			if len(headline) == 0 {
+				headline = value(item, "name")
+			}
+			articles = append(articles, Article{
+				Source:      item.Source,
+				Headline:    headline,
+				Description: value(item, "description"),
+				URL:         value(item, "url"),
+				Images:      values(item, "image", "url"),
+				Authors:     values(item, "author", "name"),
+				Publisher:   name(item, "publisher"),
+				Published:   value(item, "datePublished"),
+				Modified:    value(item, "dateModified"),
+				Section:     value(item, "articleSection"),
+			})
+		}
+	}
+	if len(articles) > 0 || !d.ogType("article") {
+		return articles
+	}
+	return []Article{{
+		Source:      SourceOpenGraph,
+		Headline:    d.og("og:title"),
+		Description: d.og("og:description"),
+		URL:         d.og("og:url"),
+		Images:      d.OpenGraph["og:image"],
+		Authors:     d.OpenGraph["article:author"],
+		Publisher:   d.og("og:site_name"),
+		Published:   d.og("article:published_time"),
+		Modified:    d.og("article:modified_time"),
+		Section:     d.og("article:section"),
+	}}
+}
+
+// og returns the first value of the OpenGraph property, or an empty
+// string.
+func (d *Data) og(property string) string {
+	if values := d.OpenGraph[property]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ogType reports whether og:type is typ, such as product or article.
+func (d *Data) ogType(typ string) bool {
+	t := d.og("og:type")
+	return t == typ || strings.HasPrefix(t, typ+".")
+}
+
+// value returns the first value of the property name as a string.
+// Numbers and booleans are formatted, nested items yield an empty
+// string.
+func value(item *Item, name string) string {
+	if values := item.Properties[name]; len(values) > 0 {
+		return str(values[0])
+	}
+	return ""
+}
+
+// values returns all values of the property name as strings. Nested
+// items yield their property key, such as the url of an ImageObject or
+// the name of a Person.
+func values(item *Item, name, key string) []string {
+	var vs []string
+	for _, v := range item.Properties[name] {
+		if i, ok := v.(*Item); ok {
+			v = i.String(key)
+		}
+		if s := str(v); len(s) > 0 {
+			vs = append(vs, s)
+		}
+	}
+	return vs
+}
+
+// name returns the first value of the property name, or the name of the
+// nested item, such as a Brand or Organization.
+func name(item *Item, property string) string {
+	if i := item.Item(property); i != nil {
+		return i.String("name")
+	}
+	return value(item, property)
+}
+
+func str(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
@@ -0,0 +1,240 @@
+// Package structured extracts structured data from HTML documents:
+// schema.org JSON-LD blocks, Microdata items, RDFa Lite items, OpenGraph
+// properties and Twitter Card tags. Data.Products and Data.Articles
+// normalize product and article metadata into typed records.
+package structured
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Sources of items.
+const (
+	SourceJSONLD    = "json-ld"
+	SourceMicrodata = "microdata"
+	SourceRDFa      = "rdfa"
+)
+
+// Item is a structured data item, such as a schema.org Product.
+//
+// Types and property names of the schema.org vocabulary are normalized
+// to their short names, such as Product and offers. Other vocabularies
+// keep their full IRIs. Property values are strings, float64 or bool
+// values, or nested *Item values.
+type Item struct {
+	Source     string                   `json:"source"`
+	Type       []string                 `json:"type,omitempty"`
+	ID         string                   `json:"id,omitempty"`
+	Properties map[string][]interface{} `json:"properties"`
+}
+
+func newItem(source string) *Item {
+	return &Item{Source: source, Properties: make(map[string][]interface{})}
+}
+
+// Is reports whether item has the type typ.
+func (item *Item) Is(typ string) bool {
+	for _, t := range item.Type {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the first value of the property name as a string. It
+// returns an empty string if the property is missing or its first value
+// is not a string.
+func (item *Item) String(name string) string {
+	if values := item.Properties[name]; len(values) > 0 {
+		if s, ok := values[0].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Item returns the first nested item of the property name, or nil.
+func (item *Item) Item(name string) *Item {
+	for _, v := range item.Properties[name] {
+		if i, ok := v.(*Item); ok {
+			return i
+		}
+	}
+	return nil
+}
+
+func (item *Item) add(name string, value interface{}) {
+	item.Properties[name] = append(item.Properties[name], value)
+}
+
+// Data is the structured data of a document.
+type Data struct {
+	Items []*Item `json:"items"`
+
+	// OpenGraph holds the values of OpenGraph properties, such as
+	// og:title or article:published_time, by property.
+	OpenGraph map[string][]string `json:"opengraph"`
+
+	// Twitter holds the Twitter Card tags, such as twitter:card, by name.
+	Twitter map[string]string `json:"twitter"`
+
+	// Errors holds the errors of invalid JSON-LD blocks.
+	Errors []error `json:"-"`
+}
+
+// Find returns the items of type typ, including nested items.
+func (d *Data) Find(typ string) []*Item {
+	var items []*Item
+	var walk func(*Item)
+	walk = func(item *Item) {
+		if item.Is(typ) {
+			items = append(items, item)
+		}
+		for _, values := range item.Properties {
+			for _, v := range values {
+				if i, ok := v.(*Item); ok {
+					walk(i)
+				}
+			}
+		}
+	}
+	for _, item := range d.Items {
+		walk(item)
+	}
+	return items
+}
+
+// Extract extracts the structured data of the document node fetched from
+// base. URL values are resolved against base if base is not nil.
+func Extract(base *url.URL, node *html.Node) *Data {
+	d := &Data{
+		OpenGraph: make(map[string][]string),
+		Twitter:   make(map[string]string),
+	}
+	d.jsonld(node)
+	d.Items = append(d.Items, microdata(base, node)...)
+	d.Items = append(d.Items, rdfa(base, node)...)
+	d.meta(node)
+	return d
+}
+
+// ogPrefixes are the property prefixes of the OpenGraph protocol.
+var ogPrefixes = []string{"og:", "article:", "book:", "profile:", "music:", "video:", "product:"}
+
+func (d *Data) meta(node *html.Node) {
+	walk(node, func(n *html.Node) bool {
+		if n.Data != "meta" {
+			return true
+		}
+		content, ok := attr(n, "content")
+		if !ok {
+			return true
+		}
+		content = strings.TrimSpace(content)
+		property, _ := attr(n, "property")
+		name, _ := attr(n, "name")
+		for _, p := range ogPrefixes {
+			if strings.HasPrefix(property, p) {
+				d.OpenGraph[property] = append(d.OpenGraph[property], content)
+				break
+			}
+		}
+		for _, key := range []string{name, property} {
+			if strings.HasPrefix(key, "twitter:") {
+				if _, found := d.Twitter[key]; !found {
+					d.Twitter[key] = content
+				}
+				break
+			}
+		}
+		return true
+	})
+}
+
+// schemaPrefixes are the IRI prefixes of the schema.org vocabulary.
+var schemaPrefixes = []string{
+	"http://schema.org/", "https://schema.org/",
+	"http://www.schema.org/", "https://www.schema.org/",
+}
+
+// isSchema reports whether vocab is the schema.org vocabulary.
+func isSchema(vocab string) bool {
+	vocab = strings.TrimSuffix(vocab, "/") + "/"
+	for _, p := range schemaPrefixes {
+		if vocab == p {
+			return true
+		}
+	}
+	return false
+}
+
+// term returns the normalized name of the type or property term in the
+// vocabulary vocab.
+func term(vocab, t string) string {
+	for _, p := range schemaPrefixes {
+		if strings.HasPrefix(t, p) {
+			return t[len(p):]
+		}
+	}
+	if strings.HasPrefix(t, "schema:") {
+		return t[len("schema:"):]
+	}
+	if len(vocab) == 0 || isSchema(vocab) || strings.Contains(t, ":") {
+		return t
+	}
+	return vocab + t
+}
+
+// walk calls fn for the elements of node in document order. Children of
+// an element are skipped if fn returns false.
+func walk(node *html.Node, fn func(*html.Node) bool) {
+	if node.Type == html.ElementNode && !fn(node) {
+		return
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attr(node *html.Node, key string) (string, bool) {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// text returns the text content of node with whitespace collapsed.
+func text(node *html.Node) string {
+	buf := &bytes.Buffer{}
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(node)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// resolve resolves the reference ref against base.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if base == nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
@@ -0,0 +1,217 @@
+package structured
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const productPage = `<html prefix="og: http://ogp.me/ns#">
+<head>
+	<meta property="og:title" content="Blue Widget">
+	<meta property="og:image" content="http://example.com/1.png">
+	<meta property="og:image" content="http://example.com/2.png">
+	<meta property="product:price:amount" content="9.99">
+	<meta name="twitter:card" content="summary">
+	<meta name="twitter:site" content="@example">
+	<meta name="description" content="A widget.">
+	<script type="application/ld+json">
+	{
+		"@context": "https://schema.org",
+		"@type": "Product",
+		"name": "Blue Widget",
+		"sku": 1234,
+		"image": ["a.png", "b.png"],
+		"offers": {"@type": "Offer", "price": "9.99", "priceCurrency": "EUR", "available": true}
+	}
+	</script>
+	<script type="application/ld+json">
+	{"@context": {"@vocab": "http://schema.org/"}, "@graph": [
+		{"@type": "BreadcrumbList", "@id": "#crumbs"},
+		{"@type": "http://example.org/Thing", "http://schema.org/name": {"@value": "thing"}}
+	]}
+	</script>
+	<script type="application/ld+json">{invalid</script>
+</head>
+<body>
+	<div itemscope itemtype="https://schema.org/Product" itemid="/p/1" itemref="extra">
+		<span itemprop="name">Red
+			Widget</span>
+		<img itemprop="image" src="red.png">
+		<div itemprop="offers" itemscope itemtype="http://schema.org/Offer">
+			<meta itemprop="priceCurrency" content="USD">
+			<data itemprop="price" value="12">twelve</data>
+		</div>
+		<a itemprop="url sameAs" href="/red">red</a>
+	</div>
+	<p id="extra" itemprop="description">A red widget.</p>
+
+	<div vocab="https://schema.org/" typeof="Article" resource="/news/1">
+		<h1 property="headline">Widgets are back</h1>
+		<time property="datePublished" datetime="2016-07-16">yesterday</time>
+		<div property="author" typeof="Person"><span property="name">Ann</span></div>
+		<a property="url" href="/news/1">link</a>
+	</div>
+</body>
+</html>`
+
+func TestExtract(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(productPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("http://example.com/shop/")
+	d := Extract(base, node)
+
+	if len(d.Errors) != 1 {
+		t.Fatalf("structured: expected 1 json-ld error, got %v", d.Errors)
+	}
+	if len(d.Items) != 5 {
+		t.Fatalf("structured: expected 5 items, got %d", len(d.Items))
+	}
+
+	// JSON-LD
+	p := d.Items[0]
+	if p.Source != SourceJSONLD || !p.Is("Product") || p.String("name") != "Blue Widget" {
+		t.Fatalf("json-ld: unexpected product %+v", p)
+	}
+	if !reflect.DeepEqual(p.Properties["sku"], []interface{}{float64(1234)}) ||
+		!reflect.DeepEqual(p.Properties["image"], []interface{}{"a.png", "b.png"}) {
+		t.Fatalf("json-ld: unexpected product properties %v", p.Properties)
+	}
+	if o := p.Item("offers"); o == nil || !o.Is("Offer") || o.String("priceCurrency") != "EUR" ||
+		o.Properties["available"][0] != true {
+		t.Fatalf("json-ld: unexpected offer %+v", o)
+	}
+	if b := d.Items[1]; !b.Is("BreadcrumbList") || b.ID != "#crumbs" {
+		t.Fatalf("json-ld: unexpected graph item %+v", b)
+	}
+	if th := d.Items[2]; !th.Is("http://example.org/Thing") || th.String("name") != "thing" {
+		t.Fatalf("json-ld: unexpected graph item %+v", th)
+	}
+
+	// Microdata
+	m := d.Items[3]
+	if m.Source != SourceMicrodata || !m.Is("Product") || m.ID != "http://example.com/p/1" {
+		t.Fatalf("microdata: unexpected item %+v", m)
+	}
+	want := map[string][]interface{}{
+		"name":        {"Red Widget"},
+		"image":       {"http://example.com/shop/red.png"},
+		"url":         {"http://example.com/red"},
+		"sameAs":      {"http://example.com/red"},
+		"description": {"A red widget."},
+	}
+	for name, values := range want {
+		if !reflect.DeepEqual(m.Properties[name], values) {
+			t.Fatalf("microdata: expected %s %v, got %v", name, values, m.Properties[name])
+		}
+	}
+	if o := m.Item("offers"); o == nil || !o.Is("Offer") || o.String("price") != "12" || o.String("priceCurrency") != "USD" {
+		t.Fatalf("microdata: unexpected offer %+v", o)
+	}
+
+	// RDFa Lite
+	a := d.Items[4]
+	if a.Source != SourceRDFa || !a.Is("Article") || a.ID != "http://example.com/news/1" {
+		t.Fatalf("rdfa: unexpected item %+v", a)
+	}
+	if a.String("headline") != "Widgets are back" || a.String("datePublished") != "2016-07-16" ||
+		a.String("url") != "http://example.com/news/1" {
+		t.Fatalf("rdfa: unexpected properties %v", a.Properties)
+	}
+	if au := a.Item("author"); au == nil || !au.Is("Person") || au.String("name") != "Ann" {
+		t.Fatalf("rdfa: unexpected author %+v", au)
+	}
+	if _, found := a.Properties["name"]; found {
+		t.Fatalf("rdfa: nested property added to parent item")
+	}
+
+	if n := len(d.Find("Offer")); n != 2 {
+		t.Fatalf("structured: expected 2 offers, got %d", n)
+	}
+
+	// OpenGraph and Twitter Cards
+	og := map[string][]string{
+		"og:title":             {"Blue Widget"},
+		"og:image":             {"http://example.com/1.png", "http://example.com/2.png"},
+		"product:price:amount": {"9.99"},
+	}
+	if !reflect.DeepEqual(d.OpenGraph, og) {
+		t.Fatalf("opengraph: expected %v, got %v", og, d.OpenGraph)
+	}
+	tw := map[string]string{"twitter:card": "summary", "twitter:site": "@example"}
+	if !reflect.DeepEqual(d.Twitter, tw) {
+		t.Fatalf("twitter: expected %v, got %v", tw, d.Twitter)
+	}
+}
+
+func TestMicrodataCycle(t *testing.T) {
+	for _, doc := range []string{
+		`<div itemscope itemref="wrap"></div><div id="wrap"><p itemprop="x" itemscope itemref="wrap"></p></div>`,
+		`<div id="self" itemscope itemref="self"><span itemprop="name">a</span></div>`,
+		`<div itemscope itemref="a"></div><p id="a" itemprop="x" itemscope itemref="b"></p><p id="b" itemprop="y" itemscope itemref="a"></p>`,
+	} {
+		node, err := html.Parse(strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := Extract(nil, node)
+		if len(d.Items) != 1 {
+			t.Fatalf("microdata %q: expected 1 item, got %d", doc, len(d.Items))
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(productPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("http://example.com/shop/")
+	d := Extract(base, node)
+
+	products := []Product{
+		{Source: SourceJSONLD, Name: "Blue Widget", Images: []string{"a.png", "b.png"}, SKU: "1234",
+			Price: "9.99", Currency: "EUR"},
+		{Source: SourceMicrodata, Name: "Red Widget", Description: "A red widget.", URL: "http://example.com/red",
+			Images: []string{"http://example.com/shop/red.png"}, Price: "12", Currency: "USD"},
+	}
+	if got := d.Products(); !reflect.DeepEqual(got, products) {
+		t.Fatalf("products: expected %+v, got %+v", products, got)
+	}
+	articles := []Article{
+		{Source: SourceRDFa, Headline: "Widgets are back", URL: "http://example.com/news/1",
+			Authors: []string{"Ann"}, Published: "2016-07-16"},
+	}
+	if got := d.Articles(); !reflect.DeepEqual(got, articles) {
+		t.Fatalf("articles: expected %+v, got %+v", articles, got)
+	}
+
+	// OpenGraph fallback
+	node, err = html.Parse(strings.NewReader(`<html><head>
+		<meta property="og:type" content="article">
+		<meta property="og:title" content="Widgets are back">
+		<meta property="og:site_name" content="Widget News">
+		<meta property="article:published_time" content="2016-07-16T10:00:00Z">
+		<meta property="article:author" content="Ann">
+		<meta property="article:author" content="Bob">
+	</head></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = Extract(nil, node)
+	articles = []Article{
+		{Source: SourceOpenGraph, Headline: "Widgets are back", Authors: []string{"Ann", "Bob"},
+			Publisher: "Widget News", Published: "2016-07-16T10:00:00Z"},
+	}
+	if got := d.Articles(); !reflect.DeepEqual(got, articles) {
+		t.Fatalf("articles: expected %+v, got %+v", articles, got)
+	}
+	if got := d.Products(); len(got) != 0 {
+		t.Fatalf("products: expected none, got %+v", got)
+	}
+}
@@ -0,0 +1,471 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// ErrNoContent is returned by ExtractArticle if a document has no main
+// content.
+var ErrNoContent = errors.New("no main content")
+
+// Article is the main content of a HTML document.
+type Article struct {
+	Title     string
+	Byline    string
+	Published time.Time // zero if unknown
+
+	// Node is the element holding the main content.
+	Node *html.Node
+
+	// Text is the text of the main content with paragraphs separated by
+	// blank lines.
+	Text string
+}
+
+var (
+	// unlikely matches class names and ids of boilerplate blocks.
+	unlikely = regexp.MustCompile(`(?i)-ad-|^ad-|ads|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tweet|twitter|widget`)
+
+	// maybe matches class names and ids which make a block a content
+	// candidate even if unlikely matches.
+	maybe = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|story|text`)
+
+	positive = regexp.MustCompile(`(?i)article|blog|body|content|entry|h-entry|hentry|main|page|post|story|text`)
+	negative = regexp.MustCompile(`(?i)-ad-|byline|comment|contact|footer|foot|hidden|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+
+	bylineClass = regexp.MustCompile(`(?i)byline|author|dateline|writtenby`)
+)
+
+// boilerplate are elements never part of the main content.
+var boilerplate = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "aside": true, "form": true, "footer": true, "header": true,
+	"iframe": true, "button": true, "select": true, "svg": true, "object": true,
+	"embed": true, "input": true, "textarea": true, "menu": true,
+}
+
+// blocks are elements separating paragraphs.
+var blocks = map[string]bool{
+	"address": true, "article": true, "blockquote": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "figcaption": true, "figure": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"hr": true, "li": true, "main": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "td": true, "th": true, "tr": true,
+	"ul": true, "br": true, "body": true, "html": true,
+}
+
+// ExtractArticle extracts the main content of the document node along
+// with its title, byline and publish date. Blocks are scored by their
+// text length, commas, class names and link density, and the best
+// scoring block and related siblings form the main content.
+func ExtractArticle(node *html.Node) (*Article, error) {
+	a := &Article{
+		Title:     articleTitle(node),
+		Byline:    byline(node),
+		Published: published(node),
+	}
+
+	top := topCandidate(node)
+	if top == nil {
+		return nil, ErrNoContent
+	}
+	a.Node = top.node
+
+	var paragraphs []string
+	for _, n := range top.content() {
+		paragraphs = append(paragraphs, contentText(n)...)
+	}
+	a.Text = strings.Join(paragraphs, "\n\n")
+	if len(a.Text) == 0 {
+		return nil, ErrNoContent
+	}
+	return a, nil
+}
+
+type candidate struct {
+	node  *html.Node
+	score float64
+	all   map[*html.Node]*candidate
+}
+
+// topCandidate returns the best scoring content candidate of node.
+func topCandidate(node *html.Node) *candidate {
+	all := make(map[*html.Node]*candidate)
+	var order []*candidate // candidates in order of discovery
+	get := func(n *html.Node) *candidate {
+		c, found := all[n]
+		if !found {
+			c = &candidate{node: n, score: initialScore(n), all: all}
+			all[n] = c
+			order = append(order, c)
+		}
+		return c
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if boilerplate[n.Data] || isUnlikely(n) {
+				return
+			}
+			switch n.Data {
+			case "p", "pre", "td", "blockquote":
+				score(n, get)
+			case "div", "section", "article":
+				if hasDirectText(n) {
+					score(n, get)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+
+	var top *candidate
+	for _, c := range order {
+		c.score *= 1 - linkDensity(c.node)
+		if top == nil || c.score > top.score {
+			top = c
+		}
+	}
+	return top
+}
+
+// score adds the content score of the paragraph n to its parent and
+// grandparent candidates.
+func score(n *html.Node, get func(*html.Node) *candidate) {
+	text := strings.Join(strings.Fields(textContent(n)), " ")
+	length := utf8.RuneCountInString(text)
+	if length < 25 {
+		return
+	}
+	s := 1 + float64(strings.Count(text, ","))
+	if length >= 300 {
+		s += 3
+	} else {
+		s += float64(length / 100)
+	}
+
+	parent := n.Parent
+	if parent == nil || parent.Type != html.ElementNode {
+		return
+	}
+	get(parent).score += s
+	if grand := parent.Parent; grand != nil && grand.Type == html.ElementNode {
+		get(grand).score += s / 2
+	}
+}
+
+func initialScore(n *html.Node) float64 {
+	var s float64
+	switch n.Data {
+	case "article":
+		s = 10
+	case "div", "main":
+		s = 5
+	case "pre", "td", "blockquote":
+		s = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		s = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		s = -5
+	}
+	return s + classWeight(n)
+}
+
+func classWeight(n *html.Node) float64 {
+	var w float64
+	for _, key := range []string{"class", "id"} {
+		v := attr(n, key)
+		if len(v) == 0 {
+			continue
+		}
+		if negative.MatchString(v) {
+			w -= 25
+		}
+		if positive.MatchString(v) {
+			w += 25
+		}
+	}
+	return w
+}
+
+// content returns the top candidate and its siblings which are likely
+// part of the main content too.
+func (top *candidate) content() []*html.Node {
+	parent := top.node.Parent
+	if parent == nil {
+		return []*html.Node{top.node}
+	}
+	threshold := top.score * 0.2
+	if threshold < 10 {
+		threshold = 10
+	}
+
+	var nodes []*html.Node
+	for n := parent.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if n == top.node {
+			nodes = append(nodes, n)
+			continue
+		}
+		if c, found := top.all[n]; found && c.score >= threshold {
+			nodes = append(nodes, n)
+			continue
+		}
+		if n.Data == "p" {
+			text := strings.Join(strings.Fields(textContent(n)), " ")
+			density := linkDensity(n)
+			length := utf8.RuneCountInString(text)
+			if (length > 80 && density < 0.25) ||
+				(length > 0 && density == 0 && strings.Contains(text, ". ")) {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes
+}
+
+// contentText returns the paragraphs of the content node n, skipping
+// boilerplate and blocks consisting mostly of links.
+func contentText(n *html.Node) []string {
+	var paragraphs []string
+	buf := &bytes.Buffer{}
+	flush := func() {
+		if p := strings.Join(strings.Fields(buf.String()), " "); len(p) > 0 {
+			paragraphs = append(paragraphs, p)
+		}
+		buf.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if boilerplate[n.Data] || (isUnlikely(n) && n.Data != "body") {
+				return
+			}
+			switch n.Data {
+			case "ul", "ol", "table", "div", "section", "dl":
+				if linkDensity(n) > 0.5 {
+					return
+				}
+			}
+		}
+		block := n.Type == html.ElementNode && blocks[n.Data]
+		if block {
+			flush()
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			flush()
+		}
+	}
+	walk(n)
+	flush()
+	return paragraphs
+}
+
+// isUnlikely reports whether the class name or id of n indicate a
+// boilerplate block.
+func isUnlikely(n *html.Node) bool {
+	if n.Data == "body" || n.Data == "article" || n.Data == "main" {
+		return false
+	}
+	if role := attr(n, "role"); role == "navigation" || role == "complementary" || role == "banner" {
+		return true
+	}
+	s := attr(n, "class") + " " + attr(n, "id")
+	return unlikely.MatchString(s) && !maybe.MatchString(s)
+}
+
+// linkDensity returns the fraction of the text of n inside links.
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(strings.Join(strings.Fields(textContent(n)), ""))
+	if total == 0 {
+		return 0
+	}
+	var links int
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			links += utf8.RuneCountInString(strings.Join(strings.Fields(textContent(n)), ""))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(total)
+}
+
+func hasDirectText(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode && len(strings.TrimSpace(c.Data)) >= 25 {
+			return true
+		}
+	}
+	return false
+}
+
+// articleTitle returns the OpenGraph title, the document title without
+// site name or the first h1 heading of node.
+func articleTitle(node *html.Node) string {
+	if t := metaContent(node, "property", "og:title"); len(t) > 0 {
+		return t
+	}
+	h1 := ""
+	if n := find(node, func(n *html.Node) bool { return n.Data == "h1" }); n != nil {
+		h1 = strings.Join(strings.Fields(textContent(n)), " ")
+	}
+	n := find(node, func(n *html.Node) bool { return n.Data == "title" })
+	if n == nil {
+		return h1
+	}
+	title := strings.Join(strings.Fields(textContent(n)), " ")
+	if len(h1) > 0 && strings.Contains(title, h1) {
+		return h1
+	}
+	for _, sep := range []string{" | ", " - ", " – ", " — ", " :: ", " » "} {
+		if i := strings.LastIndex(title, sep); i > 0 && len(strings.Fields(title[:i])) >= 3 {
+			return title[:i]
+		}
+	}
+	return title
+}
+
+// byline returns the author of the document node.
+func byline(node *html.Node) string {
+	if b := metaContent(node, "name", "author"); len(b) > 0 {
+		return b
+	}
+	n := find(node, func(n *html.Node) bool {
+		if attr(n, "rel") == "author" || attr(n, "itemprop") == "author" {
+			return true
+		}
+		return bylineClass.MatchString(attr(n, "class") + " " + attr(n, "id"))
+	})
+	if n == nil {
+		return ""
+	}
+	b := strings.Join(strings.Fields(textContent(n)), " ")
+	if len(b) == 0 || utf8.RuneCountInString(b) > 100 {
+		return ""
+	}
+	return b
+}
+
+// dateLayouts are the accepted layouts of publish dates.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// published returns the publish date of the document node.
+func published(node *html.Node) time.Time {
+	var values []string
+	for _, key := range []string{"article:published_time", "og:published_time"} {
+		values = append(values, metaContent(node, "property", key))
+	}
+	for _, key := range []string{"date", "pubdate", "publishdate", "dc.date", "dc.date.issued", "dcterms.created"} {
+		values = append(values, metaContent(node, "name", key))
+	}
+	if n := find(node, func(n *html.Node) bool { return attr(n, "itemprop") == "datePublished" }); n != nil {
+		values = append(values, attr(n, "content"), attr(n, "datetime"))
+	}
+	if n := find(node, func(n *html.Node) bool { return n.Data == "time" && len(attr(n, "datetime")) > 0 }); n != nil {
+		values = append(values, attr(n, "datetime"))
+	}
+
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// metaContent returns the content of the first meta element of node
+// whose attribute key equals value, compared case-insensitively.
+func metaContent(node *html.Node, key, value string) string {
+	n := find(node, func(n *html.Node) bool {
+		return n.Data == "meta" && strings.EqualFold(attr(n, key), value)
+	})
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(attr(n, "content"))
+}
+
+// find returns the first element of node and its descendants for which
+// match returns true, or nil.
+func find(node *html.Node, match func(*html.Node) bool) *html.Node {
+	if node.Type == html.ElementNode && match(node) {
+		return node
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if n := find(c, match); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textContent returns the concatenated text of node, skipping scripts
+// and styles.
+func textContent(node *html.Node) string {
+	buf := &bytes.Buffer{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript", "template":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return buf.String()
+}
@@ -0,0 +1,108 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const newsPage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Rivers are rising again - The Daily Example</title>
+	<meta name="author" content="Ann Smith">
+	<meta property="article:published_time" content="2016-07-16T08:30:00+02:00">
+</head>
+<body>
+	<header><a href="/">The Daily Example</a> <a href="/login">Log in</a></header>
+	<nav><ul><li><a href="/news">News</a></li><li><a href="/sports">Sports</a></li></ul></nav>
+	<div id="wrapper">
+		<div class="sidebar">
+			<h3>Popular</h3>
+			<ul>
+				<li><a href="/a">Something happened somewhere, read all about it here</a></li>
+				<li><a href="/b">Another thing happened elsewhere, and you will not believe it</a></li>
+			</ul>
+		</div>
+		<div class="ad-banner">Buy our product, it is the best product in the world, really.</div>
+		<article>
+			<h1>Rivers are rising again</h1>
+			<p class="byline">By Ann Smith</p>
+			<p>Heavy rain over the weekend has caused rivers across the region to rise, and
+			authorities have issued flood warnings for several towns along the valley.</p>
+			<p>Residents were advised to move valuables to upper floors, keep emergency kits
+			ready, and follow the instructions of local officials, who expect the water to peak on Monday.</p>
+			<div class="share"><a href="/share/fb">Share</a> <a href="/share/tw">Tweet</a></div>
+			<p>Meteorologists said the rain would ease by Tuesday, but warned that the ground
+			is saturated, so even moderate showers could lead to further flooding.</p>
+			<script>trackPageView();</script>
+		</article>
+		<div id="comments">
+			<p>First! This is a comment that is long enough to be scored as a paragraph, sadly.</p>
+		</div>
+	</div>
+	<footer><p>Copyright 2016 The Daily Example, all rights reserved, and so on and so forth.</p></footer>
+</body>
+</html>`
+
+func TestExtractArticle(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(newsPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := ExtractArticle(node)
+	if err != nil {
+		t.Fatalf("article: %v", err)
+	}
+
+	if a.Title != "Rivers are rising again" {
+		t.Fatalf("article: unexpected title %q", a.Title)
+	}
+	if a.Byline != "Ann Smith" {
+		t.Fatalf("article: unexpected byline %q", a.Byline)
+	}
+	want := time.Date(2016, 7, 16, 6, 30, 0, 0, time.UTC)
+	if !a.Published.Equal(want) {
+		t.Fatalf("article: expected published %v, got %v", want, a.Published)
+	}
+	if a.Node == nil || a.Node.Data != "article" {
+		t.Fatalf("article: expected article element, got %v", a.Node)
+	}
+
+	paragraphs := strings.Split(a.Text, "\n\n")
+	if len(paragraphs) != 5 {
+		t.Fatalf("article: expected 5 paragraphs, got %d\n%s", len(paragraphs), a.Text)
+	}
+	if !strings.HasPrefix(paragraphs[2], "Heavy rain over the weekend has caused rivers across the region to rise, and authorities") {
+		t.Fatalf("article: unexpected paragraph %q", paragraphs[2])
+	}
+	for _, noise := range []string{"Log in", "Sports", "Popular", "Buy our product", "Share", "trackPageView", "First!", "Copyright"} {
+		if strings.Contains(a.Text, noise) {
+			t.Fatalf("article: unexpected boilerplate %q in\n%s", noise, a.Text)
+		}
+	}
+}
+
+func TestExtractArticleFallbacks(t *testing.T) {
+	const page = `<html><head><title>Short | Site</title></head><body>
+<div class="post">
+	<span class="author">Bob</span> <time datetime="2016-07-01">July 1</time>
+	<p>This is the only paragraph of the post, long enough to count as content, with commas, too.</p>
+</div>
+</body></html>`
+	node, _ := html.Parse(strings.NewReader(page))
+	a, err := ExtractArticle(node)
+	if err != nil {
+		t.Fatalf("article: %v", err)
+	}
+	if a.Title != "Short | Site" || a.Byline != "Bob" || !a.Published.Equal(time.Date(2016, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("article: unexpected metadata %q, %q, %v", a.Title, a.Byline, a.Published)
+	}
+
+	node, _ = html.Parse(strings.NewReader(`<html><body><nav><a href="/">home</a></nav></body></html>`))
+	if _, err := ExtractArticle(node); err != ErrNoContent {
+		t.Fatalf("article: expected %v, got %v", ErrNoContent, err)
+	}
+}
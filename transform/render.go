@@ -0,0 +1,548 @@
+package transform
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Text renders node as readable plain text. Block elements start new
+// lines, paragraphs are separated by blank lines, list items are
+// bulleted or numbered, table rows are flattened to lines with cells
+// separated by " | ", and scripts and styles are skipped. Text content
+// is normalized with Transform.
+func Text(node *html.Node) string {
+	r := &renderer{}
+	r.render(node)
+	return r.String()
+}
+
+// Markdown renders node as CommonMark Markdown, preserving headings,
+// paragraphs, links, images, emphasis, code, block quotes and lists.
+// Tables are rendered as GitHub Flavored Markdown pipe tables. Text
+// content is normalized with Transform.
+func Markdown(node *html.Node) string {
+	r := &renderer{markdown: true}
+	r.render(node)
+	return r.String()
+}
+
+// skipped are elements not rendered.
+var skipped = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true,
+	"template": true, "iframe": true, "svg": true, "object": true,
+	"embed": true, "canvas": true, "select": true, "button": true,
+	"input": true, "textarea": true,
+}
+
+// prefix is the line prefix of a block quote or list item. The first
+// line of a list item starts with the item marker.
+type prefix struct {
+	first, rest string
+	used        bool
+}
+
+type renderer struct {
+	markdown bool
+	buf      bytes.Buffer
+	prefixes []*prefix
+	newlines int  // pending line breaks
+	space    bool // pending space between inline text
+	start    bool // at the start of a line
+	pre      int  // depth of pre elements
+}
+
+func (r *renderer) String() string {
+	return strings.TrimRight(r.buf.String(), " \n") + "\n"
+}
+
+// breakLines requests at least n line breaks before the next output.
+func (r *renderer) breakLines(n int) {
+	if r.buf.Len() == 0 && len(r.prefixes) == 0 {
+		return
+	}
+	if n > r.newlines {
+		r.newlines = n
+	}
+	r.space = false
+}
+
+// write writes inline output s, preceded by pending line breaks and the
+// line prefixes.
+func (r *renderer) write(s string) {
+	if len(s) == 0 {
+		return
+	}
+	if r.buf.Len() == 0 {
+		r.start = true
+	}
+	for i := 0; i < r.newlines; i++ {
+		if i > 0 {
+			r.buf.WriteString(strings.TrimRight(r.linePrefix(false), " "))
+		}
+		r.buf.WriteByte('\n')
+		r.start = true
+	}
+	r.newlines = 0
+	if r.start {
+		r.buf.WriteString(r.linePrefix(true))
+		r.start = false
+		r.space = false
+	}
+	if r.space {
+		r.buf.WriteByte(' ')
+		r.space = false
+	}
+	r.buf.WriteString(s)
+}
+
+// linePrefix returns the prefix of a new line. Markers of list items are
+// only used for the first content line.
+func (r *renderer) linePrefix(content bool) string {
+	var s strings.Builder
+	for _, p := range r.prefixes {
+		if !p.used && content {
+			s.WriteString(p.first)
+			p.used = true
+		} else if !p.used {
+			s.WriteString(strings.Repeat(" ", len(p.rest)))
+		} else {
+			s.WriteString(p.rest)
+		}
+	}
+	return s.String()
+}
+
+func (r *renderer) push(first, rest string) {
+	r.prefixes = append(r.prefixes, &prefix{first: first, rest: rest})
+}
+
+func (r *renderer) pop() {
+	r.prefixes = r.prefixes[:len(r.prefixes)-1]
+}
+
+// text writes inline text with whitespace collapsed.
+func (r *renderer) text(s string) {
+	if r.pre > 0 {
+		r.preformatted(s)
+		return
+	}
+	if data, _, err := Transform([]byte(s), nil); err == nil {
+		s = string(data)
+	}
+	if len(s) > 0 && isSpace(s[0]) {
+		r.space = !r.start && r.buf.Len() > 0 && r.newlines == 0
+	}
+	words := strings.Fields(s)
+	for i, w := range words {
+		if i > 0 {
+			r.space = true
+		}
+		if r.markdown {
+			w = escape(w, r.start || r.newlines > 0 || r.buf.Len() == 0)
+		}
+		r.write(w)
+	}
+	if len(words) > 0 && isSpace(s[len(s)-1]) {
+		r.space = true
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// preformatted writes s preserving whitespace and line breaks.
+func (r *renderer) preformatted(s string) {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			r.newlines++
+		}
+		if len(line) > 0 {
+			r.write(line)
+		}
+	}
+}
+
+// escape escapes Markdown syntax in the word w. If lineStart is true w
+// starts a line.
+func escape(w string, lineStart bool) string {
+	var b strings.Builder
+	for i := 0; i < len(w); i++ {
+		switch c := w[i]; c {
+		case '\\', '*', '_', '`', '[', ']', '<', '|':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	s := b.String()
+	if lineStart && len(s) > 0 {
+		switch s[0] {
+		case '#', '>', '-', '+', '=':
+			return "\\" + s
+		}
+		if i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }); i > 0 &&
+			(s[i] == '.' || s[i] == ')') {
+			return s[:i] + "\\" + s[i:]
+		}
+	}
+	return s
+}
+
+func (r *renderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.DocumentNode:
+		r.children(n)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if skipped[n.Data] {
+		return
+	}
+
+	switch n.Data {
+	case "br":
+		if r.markdown && r.buf.Len() > 0 {
+			r.write("\\")
+		}
+		r.breakLines(1)
+	case "hr":
+		r.breakLines(2)
+		if r.markdown {
+			r.write("---")
+		}
+		r.breakLines(2)
+	case "p", "address", "figure":
+		r.block(n, 2)
+	case "div", "section", "article", "main", "header", "footer", "nav", "aside",
+		"form", "fieldset", "figcaption", "details", "summary", "caption", "dt":
+		if r.markdown {
+			r.block(n, 2)
+		} else {
+			r.block(n, 1)
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.breakLines(2)
+		if r.markdown {
+			level, _ := strconv.Atoi(n.Data[1:])
+			r.write(strings.Repeat("#", level))
+			r.space = true
+		}
+		r.inline(n)
+		r.breakLines(2)
+	case "pre":
+		r.preBlock(n)
+	case "blockquote":
+		r.breakLines(2)
+		if r.markdown {
+			r.push("> ", "> ")
+		} else {
+			r.push("    ", "    ")
+		}
+		r.children(n)
+		r.pop()
+		r.breakLines(2)
+	case "ul", "ol", "menu":
+		r.list(n)
+	case "dd":
+		r.breakLines(1)
+		r.push("    ", "    ")
+		r.children(n)
+		r.pop()
+		r.breakLines(1)
+	case "table":
+		r.table(n)
+	case "a":
+		r.link(n)
+	case "img":
+		r.image(n)
+	case "strong", "b":
+		r.emphasis(n, "**")
+	case "em", "i", "cite":
+		r.emphasis(n, "*")
+	case "del", "s", "strike":
+		r.emphasis(n, "~~")
+	case "code", "kbd", "samp", "tt":
+		r.code(n)
+	default:
+		r.children(n)
+	}
+}
+
+func (r *renderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+func (r *renderer) block(n *html.Node, lines int) {
+	r.breakLines(lines)
+	r.children(n)
+	r.breakLines(lines)
+}
+
+// inline renders the children of n on a single line.
+func (r *renderer) inline(n *html.Node) {
+	s, lead, trail := r.inlineText(n)
+	r.spaced(s, lead, trail)
+}
+
+// inlineText renders the children of n on a single line. It reports
+// whether the text of n has leading and trailing whitespace, which the
+// rendered line drops.
+func (r *renderer) inlineText(n *html.Node) (s string, lead, trail bool) {
+	sub := &renderer{markdown: r.markdown}
+	sub.children(n)
+	s = strings.Join(strings.Fields(sub.buf.String()), " ")
+	t := textContent(n)
+	lead = len(t) > 0 && strings.TrimLeftFunc(t, unicode.IsSpace) != t
+	trail = len(t) > 0 && strings.TrimRightFunc(t, unicode.IsSpace) != t
+	return s, lead, trail
+}
+
+// spaced writes inline output s, separated from the surrounding text if
+// lead or trail is set.
+func (r *renderer) spaced(s string, lead, trail bool) {
+	if lead || (len(s) == 0 && trail) {
+		r.space = r.space || (!r.start && r.buf.Len() > 0 && r.newlines == 0)
+	}
+	if len(s) == 0 {
+		return
+	}
+	r.write(s)
+	if trail {
+		r.space = true
+	}
+}
+
+func (r *renderer) preBlock(n *html.Node) {
+	r.breakLines(2)
+	if r.markdown {
+		code := textContent(n)
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		lang := ""
+		if c := find(n, func(n *html.Node) bool { return n.Data == "code" }); c != nil {
+			for _, class := range strings.Fields(attr(c, "class")) {
+				if strings.HasPrefix(class, "language-") {
+					lang = strings.TrimPrefix(class, "language-")
+				}
+			}
+		}
+		r.write(fence + lang)
+		r.breakLines(1)
+		r.pre++
+		r.preformatted(strings.TrimSuffix(strings.TrimPrefix(code, "\n"), "\n"))
+		r.pre--
+		r.breakLines(1)
+		r.write(fence)
+	} else {
+		r.pre++
+		r.preformatted(strings.TrimSuffix(strings.TrimPrefix(textContent(n), "\n"), "\n"))
+		r.pre--
+	}
+	r.breakLines(2)
+}
+
+func (r *renderer) list(n *html.Node) {
+	ordered := n.Data == "ol"
+	num := 1
+	if v, err := strconv.Atoi(attr(n, "start")); err == nil && ordered {
+		num = v
+	}
+	nested := false
+	for _, p := range r.prefixes {
+		if len(p.first) > 0 && p.first != p.rest {
+			nested = true
+		}
+	}
+	if nested {
+		r.breakLines(1)
+	} else {
+		r.breakLines(2)
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+		r.breakLines(1)
+		r.push(marker, strings.Repeat(" ", len(marker)))
+		r.children(c)
+		r.pop()
+	}
+	if nested {
+		r.breakLines(1)
+	} else {
+		r.breakLines(2)
+	}
+}
+
+func (r *renderer) table(n *html.Node) {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "tr":
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						sub := &renderer{markdown: r.markdown}
+						sub.children(cell)
+						row = append(row, strings.Join(strings.Fields(sub.buf.String()), " "))
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case "table":
+				// nested tables are flattened into their cell
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return
+	}
+
+	r.breakLines(2)
+	if !r.markdown {
+		for _, row := range rows {
+			r.write(strings.Join(row, " | "))
+			r.breakLines(1)
+		}
+		r.breakLines(2)
+		return
+	}
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	line := func(cells []string) {
+		var b strings.Builder
+		b.WriteString("|")
+		for i := 0; i < cols; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		r.write(b.String())
+		r.breakLines(1)
+	}
+	line(rows[0])
+	sep := make([]string, cols)
+	for i := range sep {
+		sep[i] = "---"
+	}
+	line(sep)
+	for _, row := range rows[1:] {
+		line(row)
+	}
+	r.breakLines(2)
+}
+
+func (r *renderer) link(n *html.Node) {
+	href := strings.TrimSpace(attr(n, "href"))
+	if !r.markdown || len(href) == 0 || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		r.children(n)
+		return
+	}
+	text, lead, trail := r.inlineText(n)
+	if len(text) == 0 {
+		r.spaced("", lead, trail)
+		return
+	}
+	r.spaced("["+text+"]("+destination(href)+title(attr(n, "title"))+")", lead, trail)
+}
+
+func (r *renderer) image(n *html.Node) {
+	alt := strings.Join(strings.Fields(attr(n, "alt")), " ")
+	src := strings.TrimSpace(attr(n, "src"))
+	if !r.markdown {
+		return
+	}
+	if len(src) == 0 {
+		return
+	}
+	r.write("![" + escape(alt, false) + "](" + destination(src) + title(attr(n, "title")) + ")")
+}
+
+// destination returns the Markdown link destination of href.
+func destination(href string) string {
+	if strings.ContainsAny(href, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(href) + ">"
+	}
+	return href
+}
+
+// title returns the Markdown link title of t with a leading space, or an
+// empty string.
+func title(t string) string {
+	t = strings.Join(strings.Fields(t), " ")
+	if len(t) == 0 {
+		return ""
+	}
+	return ` "` + strings.Replace(t, `"`, `\"`, -1) + `"`
+}
+
+func (r *renderer) emphasis(n *html.Node, delim string) {
+	if !r.markdown {
+		r.children(n)
+		return
+	}
+	text, lead, trail := r.inlineText(n)
+	if len(text) == 0 {
+		r.spaced("", lead, trail)
+		return
+	}
+	r.spaced(delim+text+delim, lead, trail)
+}
+
+func (r *renderer) code(n *html.Node) {
+	code := strings.Join(strings.Fields(textContent(n)), " ")
+	if len(code) == 0 {
+		return
+	}
+	if !r.markdown {
+		r.write(code)
+		return
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	r.write(fence + code + fence)
+}
@@ -0,0 +1,123 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const renderPage = `<html><head><title>ignored</title><style>p { color: red }</style></head>
+<body>
+<h1>A   <em>great</em>
+	title</h1>
+<p>Some <strong>bold</strong> and <a href="/x y" title="X">linked</a> text<br>with a break, <code>a` + "`" + `b</code> and 1*2.</p>
+<ul>
+	<li>one</li>
+	<li>two
+		<ol start="3"><li>three</li><li>four</li></ol>
+	</li>
+</ul>
+<blockquote><p>quoted</p><p>twice</p></blockquote>
+<pre><code class="language-go">func main() {
+	println("hi")
+}</code></pre>
+<table>
+	<tr><th>Name</th><th>Value</th></tr>
+	<tr><td>a|b</td><td><a href="/v">1</a></td></tr>
+</table>
+<script>alert("no")</script>
+<div>last <img src="/i.png" alt="pic"></div>
+</body></html>`
+
+func TestText(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(renderPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `A great title
+
+Some bold and linked text
+with a break, a` + "`" + `b and 1*2.
+
+- one
+- two
+  3. three
+  4. four
+
+    quoted
+
+    twice
+
+func main() {
+	println("hi")
+}
+
+Name | Value
+a|b | 1
+
+last
+`
+	if got := Text(node); got != want {
+		t.Fatalf("text: expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(renderPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# A *great* title\n" +
+		"\n" +
+		"Some **bold** and [linked](</x y> \"X\") text\\\n" +
+		"with a break, ``a`b`` and 1\\*2.\n" +
+		"\n" +
+		"- one\n" +
+		"- two\n" +
+		"  3. three\n" +
+		"  4. four\n" +
+		"\n" +
+		"> quoted\n" +
+		">\n" +
+		"> twice\n" +
+		"\n" +
+		"```go\n" +
+		"func main() {\n" +
+		"\tprintln(\"hi\")\n" +
+		"}\n" +
+		"```\n" +
+		"\n" +
+		"| Name | Value |\n" +
+		"| --- | --- |\n" +
+		"| a\\|b | [1](/v) |\n" +
+		"\n" +
+		"last ![pic](/i.png)\n"
+	if got := Markdown(node); got != want {
+		t.Fatalf("markdown: expected\n%s\ngot\n%s", want, got)
+	}
+
+	// edge whitespace of inline elements separates words
+	for _, test := range []struct {
+		html, want string
+	}{
+		{`<p><b>bold </b>word and <a href=x>link </a>next</p>`, "**bold** word and [link](x) next\n"},
+		{`<p>a<i> b</i>c</p>`, "a *b*c\n"},
+		{`<p><b>Note: </b>text</p>`, "**Note:** text\n"},
+		{`<p>a<b> </b>b</p>`, "a b\n"},
+		{`<h2> <i>x </i>y</h2>`, "## *x* y\n"},
+	} {
+		node, _ := html.Parse(strings.NewReader(test.html))
+		if got := Markdown(node); got != test.want {
+			t.Fatalf("markdown %s: expected %q, got %q", test.html, test.want, got)
+		}
+	}
+}
+
+func TestMarkdownEscape(t *testing.T) {
+	node, _ := html.Parse(strings.NewReader(`<p># not a heading</p><p>1. not a list</p><p>a_b [c]</p>`))
+	want := "\\# not a heading\n\n1\\. not a list\n\na\\_b \\[c\\]\n"
+	if got := Markdown(node); got != want {
+		t.Fatalf("markdown: expected %q, got %q", want, got)
+	}
+}
@@ -1,42 +1,110 @@
 package transform
 
-import "golang.org/x/text/transform"
+import (
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// SpaceFunc reports whether r is a whitespace character collapsed by the
+// normalizer. Line breaks are always handled separately.
+type SpaceFunc func(r rune) bool
+
+// DefaultSpace reports whether r is a space, tab, vertical tab, form
+// feed, next line (U+0085) or no-break space (U+00A0).
+func DefaultSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\v', '\f', 0x85, 0xA0:
+		return true
+	}
+	return false
+}
+
+// ASCIISpace reports whether r is a space, tab, vertical tab or form
+// feed.
+func ASCIISpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// UnicodeSpace reports whether r is a white space character as defined
+// by unicode.IsSpace or a zero width space (U+200B).
+func UnicodeSpace(r rune) bool {
+	return unicode.IsSpace(r) || r == 0x200B
+}
 
 type normalize struct {
-	prev byte
+	space SpaceFunc
+	prev  rune
+}
+
+func (n *normalize) isSpace(r rune) bool {
+	if n.space == nil {
+		return DefaultSpace(r)
+	}
+	return n.space(r)
 }
 
 func (n *normalize) Transform(dst, src []byte, atEOF bool) (int, int, error) {
 	var nDst, nSrc int
-	for nDst < len(dst) && nSrc < len(src) {
-		c := src[nSrc]
-		switch c {
-		case ' ', '\t', '\v', '\f', 0x85, 0xA0:
-			switch n.prev {
-			case ' ', '\t', '\v', '\f', 0x85, 0xA0:
-				nSrc++
-				n.prev = c
-				continue
+	for nSrc < len(src) {
+		c, size := rune(src[nSrc]), 1
+		if c >= utf8.RuneSelf {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
 			}
-			dst[nDst] = ' '
-		case '\r':
-			dst[nDst] = '\n'
-		case '\n':
-			if n.prev == '\r' {
-				nSrc++
-				n.prev = c
-				continue
+			c, size = utf8.DecodeRune(src[nSrc:])
+		}
+
+		switch {
+		case c == '\r':
+			if nDst == len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
 			}
 			dst[nDst] = '\n'
+			nDst++
+		case c == '\n':
+			if n.prev != '\r' {
+				if nDst == len(dst) {
+					return nDst, nSrc, transform.ErrShortDst
+				}
+				dst[nDst] = '\n'
+				nDst++
+			}
+		case n.isSpace(c):
+			if !n.isSpace(n.prev) {
+				if nDst == len(dst) {
+					return nDst, nSrc, transform.ErrShortDst
+				}
+				dst[nDst] = ' '
+				nDst++
+			}
 		default:
-			dst[nDst] = c
+			// Illegal bytes decode to utf8.RuneError with size 1 and
+			// are replaced, so invalid input never turns valid.
+			w := size
+			if c == utf8.RuneError && size == 1 {
+				w = utf8.RuneLen(c)
+			}
+			if nDst+w > len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			if w == size {
+				copy(dst[nDst:], src[nSrc:nSrc+size])
+			} else {
+				utf8.EncodeRune(dst[nDst:], c)
+			}
+			nDst += w
 		}
 		n.prev = c
-		nDst++
-		nSrc++
-	}
-	if nSrc < len(src) {
-		return nDst, nSrc, transform.ErrShortDst
+		nSrc += size
 	}
 	return nDst, nSrc, nil
 }
@@ -45,24 +113,84 @@ func (n *normalize) Reset() { n.prev = 0 }
 
 type RemoveFunc func(r rune) bool
 
-func removeFunc(r rune) bool { return false }
+// Form is a Unicode normalization form.
+type Form int
+
+const (
+	// None leaves the input unnormalized.
+	None Form = iota
+	// NFC is the canonical composition.
+	NFC
+	// NFKC is the compatibility composition. It maps, for example,
+	// ligatures to their letters and no-break spaces to spaces.
+	NFKC
+)
+
+// Options configures the normalizing Transformer and Reader.
+type Options struct {
+	// Form is the Unicode normalization form applied before whitespace
+	// is collapsed.
+	Form Form
+
+	// StripControl removes control characters other than line breaks
+	// and the characters reported as whitespace by Space.
+	StripControl bool
+
+	// Space reports whitespace characters which are collapsed into a
+	// single space. If nil DefaultSpace is used.
+	Space SpaceFunc
+
+	// Remove, if not nil, removes all runes r for which Remove(r) is
+	// true.
+	Remove RemoveFunc
+}
+
+// Transformer returns a transformer which applies opts, replaces
+// carriage returns and CRLF with newlines and collapses runs of
+// whitespace into one space. It works on runes, so multi-byte UTF-8
+// sequences are never split, and illegal bytes are replaced by
+// utf8.RuneError. If opts is nil the zero Options are used.
+func Transformer(opts *Options) transform.Transformer {
+	if opts == nil {
+		opts = &Options{}
+	}
+	space := opts.Space
+	if space == nil {
+		space = DefaultSpace
+	}
+
+	var chain []transform.Transformer
+	if opts.Remove != nil {
+		chain = append(chain, runes.Remove(runes.Predicate(opts.Remove)))
+	}
+	switch opts.Form {
+	case NFC:
+		chain = append(chain, norm.NFC)
+	case NFKC:
+		chain = append(chain, norm.NFKC)
+	}
+	if opts.StripControl {
+		chain = append(chain, runes.Remove(runes.Predicate(func(r rune) bool {
+			return unicode.IsControl(r) && r != '\n' && r != '\r' && !space(r)
+		})))
+	}
+	chain = append(chain, &normalize{space: space})
+	return transform.Chain(chain...)
+}
+
+// NewReader returns a reader which normalizes r as described by
+// Transformer while reading. It can be used directly on response bodies.
+func NewReader(r io.Reader, opts *Options) io.Reader {
+	return transform.NewReader(r, Transformer(opts))
+}
 
 // Transform removes from the input all carriage returns and replaces
 // multiple whitespaces with one.  Illegal bytes in the input are
 // replaced by utf8.RuneError. Not doing so might otherwise turn a
-// sequence of invalid UTF-8 into valid UTF-8.  The resulting byte
-// sequence may subsequently contain runes for which t(r) is true that
-// were passed unnoticed.
+// sequence of invalid UTF-8 into valid UTF-8.
 //
 // If fn is not nil Transform removes from the input data all runes r
 // for which fn(r) is true.
 func Transform(data []byte, fn RemoveFunc) (result []byte, n int, err error) {
-	norm := &normalize{}
-	if fn == nil {
-		fn = removeFunc
-	}
-
-	t := transform.Chain(transform.RemoveFunc(fn), norm)
-	result, n, err = transform.Bytes(t, data)
-	return result, n, err
+	return transform.Bytes(Transformer(&Options{Remove: fn}), data)
 }
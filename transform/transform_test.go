@@ -1,7 +1,12 @@
 package transform
 
 import (
+	"bytes"
+	"io/ioutil"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"unicode"
 
 	"golang.org/x/text/transform"
 )
@@ -28,6 +33,10 @@ func TestNormalize(t *testing.T) {
 		{"hello,\t\t\tworld", "hello, world"},
 		{"\t\thello,\t\t\tworld  ", " hello, world "},
 		{"hello,\v\t\vworld", "hello, world"},
+
+		{"voil\u00e0 \u00e0", "voil\u00e0 \u00e0"},
+		{"a\u00a0\u00a0b\u0085c", "a b c"},
+		{"a\xffb", "a\ufffdb"},
 	}
 
 	n := &normalize{}
@@ -42,3 +51,52 @@ func TestNormalize(t *testing.T) {
 		}
 	}
 }
+
+func TestReader(t *testing.T) {
+	testCases := []struct {
+		in   string
+		opts *Options
+		want string
+	}{
+		{"d\u00e9j\u00e0  vu\r\n", nil, "d\u00e9j\u00e0 vu\n"},
+		{"e\u0301", &Options{Form: NFC}, "\u00e9"},
+		{"e\u0301", nil, "e\u0301"},
+		{"\ufb01ne\u00a0 \u2460", &Options{Form: NFKC, Space: ASCIISpace}, "fine 1"},
+		{"a\x00b\x1b[0m\tc\n", &Options{StripControl: true}, "ab[0m c\n"},
+		{"a\u2003\u200b b", &Options{Space: UnicodeSpace}, "a b"},
+		{"a\u2003b", nil, "a\u2003b"},
+		{"a\u00a0b", &Options{Space: ASCIISpace}, "a\u00a0b"},
+		{"ab-cd", &Options{Remove: func(r rune) bool { return r == '-' }}, "abcd"},
+		{"\u00c0 la carte", &Options{Remove: unicode.IsUpper, Form: NFC}, " la carte"},
+	}
+
+	for _, c := range testCases {
+		// read byte by byte to split runes and CRLF between reads
+		r := NewReader(iotest.OneByteReader(strings.NewReader(c.in)), c.opts)
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("reader %q: %v", c.in, err)
+			continue
+		}
+		if string(got) != c.want {
+			t.Errorf("reader %q: got %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestReaderLarge(t *testing.T) {
+	in := strings.Repeat("\u00e0\u00a0 \r\n", 10000)
+	want := strings.Repeat("\u00e0 \n", 10000)
+	got, err := ioutil.ReadAll(NewReader(bytes.NewReader([]byte(in)), nil))
+	if err != nil {
+		t.Fatalf("reader: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("reader: unexpected output of length %d", len(got))
+	}
+
+	data, n, err := Transform([]byte(in), nil)
+	if err != nil || n != len(in) || string(data) != want {
+		t.Fatalf("transform: unexpected result, n=%d err=%v", n, err)
+	}
+}
@@ -70,3 +70,29 @@ func normalizeKey(url *url.URL) string {
 	}
 	return name
 }
+
+// urlKey returns the key identifying url in caches, schedules and link
+// graphs. URLs differing only in their fragment share the same key.
+func urlKey(url *url.URL) string {
+	return url.Scheme + "://" + url.Host + normalizeKey(url)
+}
+
+// graphKey is the graph.Graph Key function of the link graphs recorded
+// by a Crawler. Invalid URLs are taken as keys.
+func graphKey(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+	return urlKey(u)
+}
+
+// RankPriority returns a priority function for Worker.PriorityFunc which
+// prioritizes URLs by scores, such as the PageRank or authority scores of
+// the link graph of a previous crawl. URLs without a score have priority
+// zero.
+func RankPriority(scores map[string]float64) func(*url.URL) float64 {
+	return func(url *url.URL) float64 {
+		return scores[urlKey(url)]
+	}
+}
@@ -0,0 +1,149 @@
+// Package warc writes WARC 1.1 files as specified by ISO 28500.
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const Version = "WARC/1.1"
+
+// Record types.
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeResponse = "response"
+	TypeRequest  = "request"
+	TypeMetadata = "metadata"
+)
+
+// Record is a single WARC record.
+type Record struct {
+	Type        string
+	TargetURI   string
+	Date        time.Time
+	ContentType string
+
+	// Header holds additional named fields, such as WARC-IP-Address.
+	Header map[string]string
+
+	Block []byte
+}
+
+// NewResponse returns a response record of a HTTP response with the
+// status code, headers and body fetched from target at date.
+func NewResponse(target string, date time.Time, status int, header http.Header, body []byte) *Record {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	header.Write(buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	return &Record{
+		Type:        TypeResponse,
+		TargetURI:   target,
+		Date:        date,
+		ContentType: "application/http;msgtype=response",
+		Block:       buf.Bytes(),
+	}
+}
+
+// NewWarcinfo returns a warcinfo record describing the software writing
+// the file.
+func NewWarcinfo(software string, date time.Time) *Record {
+	block := fmt.Sprintf("software: %s\r\nformat: WARC File Format 1.1\r\n", software)
+	return &Record{
+		Type:        TypeWarcinfo,
+		Date:        date,
+		ContentType: "application/warc-fields",
+		Block:       []byte(block),
+	}
+}
+
+// Writer writes WARC records. It is safe for concurrent use.
+type Writer struct {
+	// Compress enables writing every record as a separate gzip member,
+	// as used by .warc.gz files.
+	Compress bool
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter returns a writer writing records to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes the record r.
+func (w *Writer) Write(r *Record) error {
+	id, err := newRecordID()
+	if err != nil {
+		return err
+	}
+	date := r.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(Version + "\r\n")
+	field(buf, "WARC-Type", r.Type)
+	field(buf, "WARC-Record-ID", id)
+	field(buf, "WARC-Date", date.UTC().Format(time.RFC3339))
+	if len(r.TargetURI) > 0 {
+		field(buf, "WARC-Target-URI", r.TargetURI)
+	}
+	if len(r.ContentType) > 0 {
+		field(buf, "Content-Type", r.ContentType)
+	}
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field(buf, name, r.Header[name])
+	}
+	field(buf, "Content-Length", strconv.Itoa(len(r.Block)))
+	buf.WriteString("\r\n")
+	buf.Write(r.Block)
+	buf.WriteString("\r\n\r\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.Compress {
+		_, err = w.w.Write(buf.Bytes())
+		return err
+	}
+	zw := gzip.NewWriter(w.w)
+	if _, err = zw.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func field(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+// newRecordID returns a random version 4 UUID URN.
+func newRecordID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
@@ -0,0 +1,82 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var date = time.Date(2016, 7, 16, 12, 0, 0, 0, time.UTC)
+
+func TestWriteResponse(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+
+	header := http.Header{"Content-Type": {"text/html"}}
+	if err := w.Write(NewResponse("http://example.com/", date, 200, header, []byte("<html></html>"))); err != nil {
+		t.Fatalf("warc: write: %v", err)
+	}
+
+	block := "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<html></html>"
+	want := regexp.MustCompile("^WARC/1.1\r\n" +
+		"WARC-Type: response\r\n" +
+		"WARC-Record-ID: <urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}>\r\n" +
+		"WARC-Date: 2016-07-16T12:00:00Z\r\n" +
+		"WARC-Target-URI: http://example.com/\r\n" +
+		"Content-Type: application/http;msgtype=response\r\n" +
+		"Content-Length: " + strconv.Itoa(len(block)) + "\r\n" +
+		"\r\n" + regexp.QuoteMeta(block) + "\r\n\r\n$")
+	if !want.Match(buf.Bytes()) {
+		t.Fatalf("warc: unexpected record %q", buf)
+	}
+}
+
+func TestWriteCompressed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	w.Compress = true
+
+	if err := w.Write(NewWarcinfo("crawler", date)); err != nil {
+		t.Fatalf("warc: write: %v", err)
+	}
+	if err := w.Write(NewResponse("http://example.com/", date, 404, http.Header{}, nil)); err != nil {
+		t.Fatalf("warc: write: %v", err)
+	}
+
+	// every record is a separate gzip member
+	br := bufio.NewReader(buf)
+	var records []string
+	for {
+		zr, err := gzip.NewReader(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("warc: gzip: %v", err)
+		}
+		zr.Multistream(false)
+		data, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("warc: gzip: %v", err)
+		}
+		records = append(records, string(data))
+	}
+	if len(records) != 2 {
+		t.Fatalf("warc: expected 2 gzip members, got %d", len(records))
+	}
+	if !strings.Contains(records[0], "WARC-Type: warcinfo\r\n") ||
+		!strings.HasSuffix(records[0], "software: crawler\r\nformat: WARC File Format 1.1\r\n\r\n\r\n") {
+		t.Fatalf("warc: unexpected warcinfo record %q", records[0])
+	}
+	if !strings.Contains(records[1], "HTTP/1.1 404 Not Found\r\n") {
+		t.Fatalf("warc: unexpected response record %q", records[1])
+	}
+}